@@ -0,0 +1,31 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+// authenticate rejects requests without a valid API key and counts the
+// others against the key's limits, which are reported in X-RateLimit-* headers.
+func (s *GinServer) authenticate(c *gin.Context) {
+	_, limits, err := s.auth.Authenticate(c.Request.Context(), c.GetHeader(apiKeyHeader))
+	if limits.Limit > 0 {
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limits.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(limits.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(limits.Reset.Unix(), 10))
+	}
+	if err != nil {
+		var rateLimitErr *types.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			c.Header("Retry-After", strconv.Itoa(retryAfterSeconds(rateLimitErr.RetryAfter)))
+		}
+		respondWithError(c, err)
+		return
+	}
+	c.Next()
+}
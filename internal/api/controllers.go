@@ -1,73 +1,421 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
-	"github.com/sirupsen/logrus"
+	"github.com/wojcikp/currency-converter/internal/types"
 )
 
 func (s *GinServer) GetRates(c *gin.Context) {
-	param := c.Query("currencies")
-	if param == "" {
-		logrus.Error(`url parameter "currencies" not provided`)
-		c.JSON(http.StatusBadRequest, gin.H{})
+	if err := requireQueryParams(c, "currencies"); err != nil {
+		respondWithError(c, err)
 		return
 	}
 
-	validatedCurrencies, err := validateCurrencies(strings.Split(param, ","))
+	validatedCurrencies, err := validateCurrencies(strings.Split(c.Query("currencies"), ","))
 	if err != nil {
-		logrus.Error(err)
-		c.JSON(http.StatusBadRequest, gin.H{})
+		respondWithError(c, err)
 		return
-
 	}
 
 	rates, err := s.converter.GetCurrenciesRates(c.Request.Context(), validatedCurrencies)
 	if err != nil {
-		logrus.Error(err)
-		c.JSON(http.StatusBadRequest, gin.H{})
+		respondWithError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, rates)
+	setRatesSourceHeader(c, rates.Source)
+	c.JSON(http.StatusOK, rates.Rates)
 }
 
-func (s *GinServer) ExchangeCryptoCurrencies(c *gin.Context) {
-	from := c.Query("from")
-	to := c.Query("to")
-	amount := c.Query("amount")
+// maxMatrixCurrencies bounds the matrix size, the response grows with its square.
+const maxMatrixCurrencies = 50
 
-	if from == "" || to == "" || amount == "" {
-		logrus.Errorf("missing one of parameters: from, to or amount. parameters: from: %s, to: %s, amount: %s", from, to, amount)
-		c.JSON(http.StatusBadRequest, gin.H{})
+func (s *GinServer) GetRatesMatrix(c *gin.Context) {
+	if err := requireQueryParams(c, "currencies"); err != nil {
+		respondWithError(c, err)
 		return
 	}
 
-	decimalAmount, err := decimal.NewFromString(amount)
+	validatedCurrencies, err := validateCurrencies(strings.Split(c.Query("currencies"), ","))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	if len(validatedCurrencies) > maxMatrixCurrencies {
+		respondWithError(c, &types.InvalidInputError{
+			Field:  "currencies",
+			Value:  c.Query("currencies"),
+			Reason: fmt.Sprintf("at most %d currencies are allowed", maxMatrixCurrencies),
+		})
+		return
+	}
+
+	matrix, err := s.converter.GetRatesMatrix(c.Request.Context(), validatedCurrencies, strings.ToUpper(strings.TrimSpace(c.Query("base"))))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	setRatesSourceHeader(c, matrix.Source)
+	c.JSON(http.StatusOK, matrix)
+}
+
+func (s *GinServer) GetLatestRates(c *gin.Context) {
+	base := strings.ToUpper(strings.TrimSpace(c.DefaultQuery("base", "USD")))
+	var symbols []string
+	for _, symbol := range strings.Split(c.Query("symbols"), ",") {
+		if symbol = strings.ToUpper(strings.TrimSpace(symbol)); symbol != "" && !slices.Contains(symbols, symbol) {
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	latest, err := s.converter.GetLatestRates(c.Request.Context(), base, symbols)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	setRatesSourceHeader(c, latest.Source)
+	c.JSON(http.StatusOK, latest)
+}
+
+func (s *GinServer) GetCurrencies(c *gin.Context) {
+	assetType := strings.ToLower(c.Query("type"))
+	if assetType != "" && assetType != types.AssetTypeFiat && assetType != types.AssetTypeCrypto {
+		respondWithError(c, &types.InvalidInputError{
+			Field:  "type",
+			Value:  c.Query("type"),
+			Reason: fmt.Sprintf("expected %s or %s", types.AssetTypeFiat, types.AssetTypeCrypto),
+		})
+		return
+	}
+
+	catalogue, err := s.converter.GetCurrencies(c.Request.Context(), assetType)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, catalogue)
+}
+
+func (s *GinServer) GetHistoricalRates(c *gin.Context) {
+	if err := requireQueryParams(c, "date", "currencies"); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	date, err := parseDate("date", c.Query("date"), earliestHistoricalDate)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	validatedCurrencies, err := validateCurrencies(strings.Split(c.Query("currencies"), ","))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	rates, err := s.converter.GetHistoricalCurrenciesRates(c.Request.Context(), date, validatedCurrencies)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	setRatesSourceHeader(c, rates.Source)
+	c.JSON(http.StatusOK, rates.Rates)
+}
+
+func (s *GinServer) GetTimeSeries(c *gin.Context) {
+	if err := requireQueryParams(c, "from", "to", "start", "end"); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	start, err := parseDate("start", c.Query("start"), earliestHistoricalDate)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	end, err := parseDate("end", c.Query("end"), earliestHistoricalDate)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	timeSeries, err := s.converter.GetTimeSeries(c.Request.Context(), types.TimeSeriesRequest{
+		From:     strings.ToUpper(c.Query("from")),
+		To:       strings.ToUpper(c.Query("to")),
+		Start:    start,
+		End:      end,
+		Interval: c.DefaultQuery("interval", types.IntervalDay),
+	})
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, timeSeries)
+}
+
+func (s *GinServer) ExchangeCurrencies(c *gin.Context) {
+	from, to, amount, err := parseConversionParams(c)
 	if err != nil {
-		logrus.Error("could not parse parameter amount to decimal. amount: ", amount)
-		c.JSON(http.StatusBadRequest, gin.H{})
+		respondWithError(c, err)
 		return
 	}
 
-	exchangedCrypto, err := s.converter.ConvertCryptoCurrencies(
-		c.Request.Context(),
-		strings.ToUpper(from),
-		strings.ToUpper(to),
-		decimalAmount,
-	)
+	exchanged, err := s.converter.ExchangeCurrencies(c.Request.Context(), from, to, amount)
 	if err != nil {
-		logrus.Error(err)
-		c.JSON(http.StatusBadRequest, gin.H{})
+		respondWithError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, exchangedCrypto)
+	setRatesSourceHeader(c, exchanged.Source)
+	c.JSON(http.StatusOK, exchanged)
 }
 
+func (s *GinServer) ConvertCurrencies(c *gin.Context) {
+	from, to, amount, err := parseConversionParams(c)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	converted, err := s.converter.ConvertCurrencies(c.Request.Context(), from, to, amount)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	setRatesSourceHeader(c, converted.Source)
+	c.JSON(http.StatusOK, converted)
+}
+
+// maxBatchItems bounds a single batch so that one request cannot hold the
+// server for too long.
+const maxBatchItems = 10000
+
+// maxBatchItemBytes is generous for one item with a long id and amount, it
+// sizes the body limit so that oversized batches are rejected before decoding.
+const maxBatchItemBytes = 256
+
+// batchConversionResponse carries the timestamps of both snapshots, the crypto
+// rates are refreshed independently of the fiat ones.
+type batchConversionResponse struct {
+	Source          string                  `json:"source"`
+	Timestamp       time.Time               `json:"timestamp"`
+	CryptoTimestamp time.Time               `json:"crypto_timestamp"`
+	Results         []batchConversionResult `json:"results"`
+}
+
+type batchConversionResult struct {
+	ID     string                   `json:"id"`
+	Result *types.ExchangedCurrency `json:"result,omitempty"`
+	Error  *ErrorResponse           `json:"error,omitempty"`
+}
+
+func (s *GinServer) ConvertBatch(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBatchItems*maxBatchItemBytes)
+	var items []types.BatchConversionItem
+	if err := c.ShouldBindJSON(&items); err != nil {
+		var tooLargeErr *http.MaxBytesError
+		if errors.As(err, &tooLargeErr) {
+			respondWithError(c, tooLargeErr)
+			return
+		}
+		respondWithError(c, &types.InvalidInputError{Field: "body", Reason: err.Error()})
+		return
+	}
+	if len(items) == 0 || len(items) > maxBatchItems {
+		respondWithError(c, &types.InvalidInputError{
+			Field:  "body",
+			Value:  strconv.Itoa(len(items)),
+			Reason: fmt.Sprintf("batch must contain between 1 and %d items", maxBatchItems),
+		})
+		return
+	}
+	for i := range items {
+		items[i].From, items[i].To = strings.ToUpper(items[i].From), strings.ToUpper(items[i].To)
+	}
+
+	batch, err := s.converter.ExchangeCurrenciesBatch(c.Request.Context(), items)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	response := batchConversionResponse{
+		Source:          batch.Source,
+		Timestamp:       batch.Timestamp,
+		CryptoTimestamp: batch.CryptoTimestamp,
+		Results:         make([]batchConversionResult, len(batch.Conversions)),
+	}
+	for i, conversion := range batch.Conversions {
+		response.Results[i].ID = conversion.ID
+		if conversion.Err != nil {
+			_, errResponse := errorResponse(conversion.Err)
+			response.Results[i].Error = &errResponse
+			continue
+		}
+		response.Results[i].Result = &conversion.Result
+	}
+
+	setRatesSourceHeader(c, batch.Source)
+	c.JSON(http.StatusOK, response)
+}
+
+type quoteRequest struct {
+	From   string          `json:"from"`
+	To     string          `json:"to"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+func (s *GinServer) CreateQuote(c *gin.Context) {
+	var request quoteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondWithError(c, &types.InvalidInputError{Field: "body", Reason: err.Error()})
+		return
+	}
+	if request.From == "" || request.To == "" {
+		respondWithError(c, &types.InvalidInputError{Field: "body", Reason: "from and to are required"})
+		return
+	}
+
+	quote, err := s.quotes.CreateQuote(c.Request.Context(), strings.ToUpper(request.From), strings.ToUpper(request.To), request.Amount)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	setRatesSourceHeader(c, quote.Source)
+	c.JSON(http.StatusCreated, quote)
+}
+
+func (s *GinServer) ExecuteQuote(c *gin.Context) {
+	quote, err := s.quotes.ExecuteQuote(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, quote)
+}
+
+// GetNBPBidAskRates serves NBP table C, the table published on date or on the
+// last business day before it when date is given.
+func (s *GinServer) GetNBPBidAskRates(c *gin.Context) {
+	var rates types.BidAskRates
+	var err error
+	if c.Query("date") != "" {
+		date, dateErr := parseDate("date", c.Query("date"), earliestNBPDate)
+		if dateErr != nil {
+			respondWithError(c, dateErr)
+			return
+		}
+		rates, err = s.nbp.GetHistoricalBidAskRates(c.Request.Context(), date)
+	} else {
+		rates, err = s.nbp.GetBidAskRates(c.Request.Context())
+	}
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rates)
+}
+
+func (s *GinServer) GetNBPTaxRate(c *gin.Context) {
+	if err := requireQueryParams(c, "currency", "date"); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	// the tax rule needs a table published before date
+	date, err := parseDate("date", c.Query("date"), earliestNBPDate.AddDate(0, 0, 1))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	rate, err := s.nbp.GetTaxRate(c.Request.Context(), strings.ToUpper(c.Query("currency")), date)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rate)
+}
+
+// Liveness only reports that the process serves requests, dependencies are
+// checked by Readiness.
+func (s *GinServer) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func (s *GinServer) Readiness(c *gin.Context) {
+	readiness := s.health.CheckReadiness(c.Request.Context())
+	status := http.StatusOK
+	if !readiness.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, readiness)
+}
+
+// setRatesSourceHeader reports which rates provider served the snapshot, which
+// matters when a fallback provider had to step in.
+func setRatesSourceHeader(c *gin.Context, source string) {
+	c.Header("X-Rates-Source", source)
+}
+
+func parseConversionParams(c *gin.Context) (string, string, decimal.Decimal, error) {
+	if err := requireQueryParams(c, "from", "to", "amount"); err != nil {
+		return "", "", decimal.Decimal{}, err
+	}
+	amount := c.Query("amount")
+	decimalAmount, err := decimal.NewFromString(amount)
+	if err != nil {
+		return "", "", decimal.Decimal{}, &types.InvalidInputError{Field: "amount", Value: amount, Reason: "not a decimal number"}
+	}
+	if !decimalAmount.IsPositive() {
+		return "", "", decimal.Decimal{}, &types.InvalidInputError{Field: "amount", Value: amount, Reason: "must be positive"}
+	}
+	return strings.ToUpper(c.Query("from")), strings.ToUpper(c.Query("to")), decimalAmount, nil
+}
+
+func requireQueryParams(c *gin.Context, names ...string) error {
+	for _, name := range names {
+		if c.Query(name) == "" {
+			return &types.InvalidInputError{Field: name, Reason: "url parameter not provided"}
+		}
+	}
+	return nil
+}
+
+var (
+	// earliestHistoricalDate is the first day covered by openexchangerates.org history.
+	earliestHistoricalDate = time.Date(1999, time.January, 1, 0, 0, 0, 0, time.UTC)
+	// earliestNBPDate is the day of the first table in the NBP api.
+	earliestNBPDate = time.Date(2002, time.January, 2, 0, 0, 0, 0, time.UTC)
+)
+
+func parseDate(field, value string, earliest time.Time) (time.Time, error) {
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, &types.InvalidInputError{Field: field, Value: value, Reason: "expected date in YYYY-MM-DD format"}
+	}
+	if date.Before(earliest) {
+		return time.Time{}, &types.InvalidInputError{Field: field, Value: value, Reason: "rates are available from " + earliest.Format(time.DateOnly)}
+	}
+	if date.After(time.Now().UTC()) {
+		return time.Time{}, &types.InvalidInputError{Field: field, Value: value, Reason: "date must not be in the future"}
+	}
+	return date, nil
+}
+
+// validateCurrencies normalizes and dedupes the currencies, keeping the order
+// of first occurrence.
 func validateCurrencies(currencies []string) ([]string, error) {
 	currenciesSet := map[string]struct{}{}
 	var validatedCurrencies []string
@@ -78,13 +426,15 @@ func validateCurrencies(currencies []string) ([]string, error) {
 		}
 		if _, ok := currenciesSet[currency]; !ok {
 			currenciesSet[currency] = struct{}{}
+			validatedCurrencies = append(validatedCurrencies, currency)
 		}
 	}
-	if len(currenciesSet) < 2 {
-		return nil, fmt.Errorf("not enough currencies to exchange provided, currencies: %s", currencies)
-	}
-	for currency := range currenciesSet {
-		validatedCurrencies = append(validatedCurrencies, currency)
+	if len(validatedCurrencies) < 2 {
+		return nil, &types.InvalidInputError{
+			Field:  "currencies",
+			Value:  strings.Join(currencies, ","),
+			Reason: "at least two distinct currencies are required",
+		}
 	}
 	return validatedCurrencies, nil
 }
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+const (
+	CodeInvalidRequest      = "invalid_request"
+	CodeUnknownCurrency     = "unknown_currency"
+	CodeProviderError       = "provider_error"
+	CodeProviderUnavailable = "provider_unavailable"
+	CodeQuoteNotFound       = "quote_not_found"
+	CodeQuoteExpired        = "quote_expired"
+	CodeUnauthorized        = "unauthorized"
+	CodeRateLimited         = "rate_limited"
+	CodeQuotaExceeded       = "quota_exceeded"
+	CodeRequestTimeout      = "request_timeout"
+	CodeRequestTooLarge     = "request_too_large"
+	CodeInternalError       = "internal_error"
+)
+
+type ErrorResponse struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+func respondWithError(c *gin.Context, err error) {
+	logrus.Error(err)
+	status, response := errorResponse(err)
+	c.AbortWithStatusJSON(status, response)
+}
+
+func errorResponse(err error) (int, ErrorResponse) {
+	var invalidInputErr *types.InvalidInputError
+	var unknownCurrencyErr *types.UnknownCurrencyError
+	var providerErr *types.ProviderError
+	var quoteNotFoundErr *types.QuoteNotFoundError
+	var quoteExpiredErr *types.QuoteExpiredError
+	var unauthorizedErr *types.UnauthorizedError
+	var rateLimitErr *types.RateLimitError
+	var tooLargeErr *http.MaxBytesError
+
+	// timeouts come first, providers wrap them in a ProviderError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return http.StatusGatewayTimeout, ErrorResponse{
+			Code:    CodeRequestTimeout,
+			Message: "request was cancelled before rates could be served",
+		}
+	case errors.As(err, &invalidInputErr):
+		return http.StatusBadRequest, ErrorResponse{
+			Code:    CodeInvalidRequest,
+			Message: invalidInputErr.Error(),
+			Details: map[string]string{"field": invalidInputErr.Field, "value": invalidInputErr.Value},
+		}
+	case errors.As(err, &unknownCurrencyErr):
+		return http.StatusNotFound, ErrorResponse{
+			Code:    CodeUnknownCurrency,
+			Message: unknownCurrencyErr.Error(),
+			Details: map[string]string{"currency": unknownCurrencyErr.Currency},
+		}
+	case errors.As(err, &providerErr):
+		status, code := http.StatusBadGateway, CodeProviderError
+		if providerErr.Unavailable {
+			status, code = http.StatusServiceUnavailable, CodeProviderUnavailable
+		}
+		return status, ErrorResponse{
+			Code:    code,
+			Message: "exchange rates provider failed to serve the request",
+			Details: map[string]string{"provider": providerErr.Provider},
+		}
+	case errors.As(err, &quoteNotFoundErr):
+		return http.StatusNotFound, ErrorResponse{
+			Code:    CodeQuoteNotFound,
+			Message: quoteNotFoundErr.Error(),
+			Details: map[string]string{"id": quoteNotFoundErr.ID},
+		}
+	case errors.As(err, &quoteExpiredErr):
+		return http.StatusGone, ErrorResponse{
+			Code:    CodeQuoteExpired,
+			Message: quoteExpiredErr.Error(),
+			Details: map[string]string{"id": quoteExpiredErr.ID, "expires_at": quoteExpiredErr.ExpiresAt.Format(time.RFC3339)},
+		}
+	case errors.As(err, &unauthorizedErr):
+		return http.StatusUnauthorized, ErrorResponse{
+			Code:    CodeUnauthorized,
+			Message: unauthorizedErr.Error(),
+		}
+	case errors.As(err, &rateLimitErr):
+		code := CodeRateLimited
+		if rateLimitErr.Quota {
+			code = CodeQuotaExceeded
+		}
+		return http.StatusTooManyRequests, ErrorResponse{
+			Code:    code,
+			Message: rateLimitErr.Error(),
+			Details: map[string]string{"key_id": rateLimitErr.KeyID, "retry_after": strconv.Itoa(retryAfterSeconds(rateLimitErr.RetryAfter))},
+		}
+	case errors.As(err, &tooLargeErr):
+		return http.StatusRequestEntityTooLarge, ErrorResponse{
+			Code:    CodeRequestTooLarge,
+			Message: fmt.Sprintf("request body exceeds %d bytes", tooLargeErr.Limit),
+			Details: map[string]string{"limit": strconv.FormatInt(tooLargeErr.Limit, 10)},
+		}
+	default:
+		return http.StatusInternalServerError, ErrorResponse{
+			Code:    CodeInternalError,
+			Message: "internal server error",
+		}
+	}
+}
+
+// retryAfterSeconds rounds up, so that clients never retry too early.
+func retryAfterSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
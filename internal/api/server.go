@@ -6,6 +6,9 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/wojcikp/currency-converter/internal/auth"
+	"github.com/wojcikp/currency-converter/internal/metrics"
+	ratesstream "github.com/wojcikp/currency-converter/internal/rates_stream"
 	"github.com/wojcikp/currency-converter/internal/types"
 )
 
@@ -13,26 +16,61 @@ type GinServer struct {
 	router    *gin.Engine
 	server    *http.Server
 	converter types.Converter
+	quotes    types.QuoteService
+	metrics   *metrics.Metrics
+	health    types.ReadinessChecker
+	// auth is nil when API keys are disabled and the API is open.
+	auth    *auth.Authenticator
+	streams *ratesstream.Hub
+	nbp     types.NBPRatesProvider
 }
 
-func NewGinServer(serverPort string, converter types.Converter) *GinServer {
+func NewGinServer(
+	serverPort string,
+	converter types.Converter,
+	quotes types.QuoteService,
+	metrics *metrics.Metrics,
+	health types.ReadinessChecker,
+	auth *auth.Authenticator,
+	streams *ratesstream.Hub,
+	nbp types.NBPRatesProvider,
+) *GinServer {
 	r := gin.Default()
 	return &GinServer{
 		router:    r,
 		server:    &http.Server{Addr: fmt.Sprintf(":%s", serverPort), Handler: r},
 		converter: converter,
+		quotes:    quotes,
+		metrics:   metrics,
+		health:    health,
+		auth:      auth,
+		streams:   streams,
+		nbp:       nbp,
 	}
 }
 
 func (s *GinServer) RegisterRoutes() {
-	s.router.GET("/rates", s.GetRates)
-	s.router.GET("/exchange", s.ExchangeCryptoCurrencies)
+	s.router.Use(s.metrics.GinMiddleware())
+	s.router.GET("/metrics", gin.WrapH(s.metrics.Handler()))
+	s.router.GET("/healthz", s.Liveness)
+	s.router.GET("/readyz", s.Readiness)
+	s.router.GET("/openapi.json", s.GetOpenAPISpec)
+	s.router.GET("/docs", s.GetDocs)
+	s.router.GET("/docs/swagger-ui.css", s.GetDocsCSS)
+	s.router.GET("/docs/swagger-ui-bundle.js", s.GetDocsBundle)
+
+	s.registerV1(s.versionGroup("/v1"))
+	// root routes predate versioning, they stay as deprecated aliases of /v1
+	s.registerV1(s.versionGroup("", deprecatedAliasOf("/v1")))
 }
 
 func (s *GinServer) Run() error {
 	return s.server.ListenAndServe()
 }
 
+// Shutdown ends open rate streams first, http.Server.Shutdown would otherwise
+// wait for them until ctx expires.
 func (s *GinServer) Shutdown(ctx context.Context) error {
+	s.streams.Close()
 	return s.server.Shutdown(ctx)
 }
@@ -1,27 +1,138 @@
 package api
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
-	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
 	"github.com/gin-gonic/gin"
 	"github.com/google/go-cmp/cmp"
 	"github.com/shopspring/decimal"
+	"github.com/wojcikp/currency-converter/internal/auth"
 	currencyconverter "github.com/wojcikp/currency-converter/internal/currency_converter"
 	exchangeratesprovider "github.com/wojcikp/currency-converter/internal/exchange_rates_provider"
+	"github.com/wojcikp/currency-converter/internal/fees"
+	"github.com/wojcikp/currency-converter/internal/metrics"
+	"github.com/wojcikp/currency-converter/internal/quotes"
+	ratesstream "github.com/wojcikp/currency-converter/internal/rates_stream"
 	"github.com/wojcikp/currency-converter/internal/types"
 )
 
+type stubReadinessChecker struct {
+	readiness types.Readiness
+}
+
+func (c stubReadinessChecker) CheckReadiness(ctx context.Context) types.Readiness {
+	return c.readiness
+}
+
+// stubNBPRates serves fixed NBP tables, EUR is the only currency with a tax rate.
+type stubNBPRates struct{}
+
+func (stubNBPRates) GetBidAskRates(ctx context.Context) (types.BidAskRates, error) {
+	return types.BidAskRates{
+		Table:         "124/C/NBP/2025",
+		EffectiveDate: time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC),
+		Rates:         map[string]types.BidAskRate{"EUR": {Bid: decimal.RequireFromString("4.1995"), Ask: decimal.RequireFromString("4.2843")}},
+	}, nil
+}
+
+func (stubNBPRates) GetHistoricalBidAskRates(ctx context.Context, date time.Time) (types.BidAskRates, error) {
+	return types.BidAskRates{
+		Table:         "123/C/NBP/2025",
+		EffectiveDate: time.Date(2025, time.June, 27, 0, 0, 0, 0, time.UTC),
+		Rates:         map[string]types.BidAskRate{"EUR": {Bid: decimal.RequireFromString("4.2019"), Ask: decimal.RequireFromString("4.2867")}},
+	}, nil
+}
+
+func (stubNBPRates) GetTaxRate(ctx context.Context, currency string, date time.Time) (types.TaxRate, error) {
+	if currency != "EUR" {
+		return types.TaxRate{}, &types.UnknownCurrencyError{Currency: currency, Source: "nbp"}
+	}
+	return types.TaxRate{
+		Currency:      currency,
+		Date:          date,
+		Rate:          decimal.RequireFromString("4.2443"),
+		Table:         "123/A/NBP/2025",
+		EffectiveDate: time.Date(2025, time.June, 27, 0, 0, 0, 0, time.UTC),
+	}, nil
+}
+
+// fakeUpdates signals rate changes to every subscriber on demand.
+type fakeUpdates struct {
+	mu          sync.Mutex
+	subscribers map[chan struct{}]struct{}
+}
+
+func newFakeUpdates() *fakeUpdates {
+	return &fakeUpdates{subscribers: map[chan struct{}]struct{}{}}
+}
+
+func (u *fakeUpdates) Subscribe() (<-chan struct{}, func()) {
+	updates := make(chan struct{}, 1)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.subscribers[updates] = struct{}{}
+	return updates, func() {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		delete(u.subscribers, updates)
+	}
+}
+
+func (u *fakeUpdates) signal() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for updates := range u.subscribers {
+		select {
+		case updates <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// newTestServer builds a server over the mock provider with every route
+// registered, for tests that exercise middleware.
+func newTestServer(readiness types.Readiness, authenticator *auth.Authenticator) *GinServer {
+	provider := exchangeratesprovider.NewExchangeRatesProviderMock()
+	converter := currencyconverter.NewConverter(provider, provider, fees.Schedule{})
+	quoteService := quotes.NewService(converter, quotes.NewMemoryStore(), quotes.DefaultTTL)
+	server := NewGinServer("8080", converter, quoteService, metrics.New(), stubReadinessChecker{readiness}, authenticator, ratesstream.NewHub(newFakeUpdates()), stubNBPRates{})
+	server.RegisterRoutes()
+	return server
+}
+
 func setupRouter() *gin.Engine {
 	provider := exchangeratesprovider.NewExchangeRatesProviderMock()
-	converter := currencyconverter.NewConverter(provider)
-	server := NewGinServer("8080", converter)
+	converter := currencyconverter.NewConverter(provider, provider, fees.Schedule{})
+	server := NewGinServer("8080", converter, quotes.NewService(converter, quotes.NewMemoryStore(), quotes.DefaultTTL), metrics.New(), stubReadinessChecker{}, nil, nil, stubNBPRates{})
 	router := gin.Default()
 	router.GET("/rates", server.GetRates)
-	router.GET("/exchange", server.ExchangeCryptoCurrencies)
+	router.GET("/rates/historical", server.GetHistoricalRates)
+	router.GET("/rates/matrix", server.GetRatesMatrix)
+	router.GET("/latest", server.GetLatestRates)
+	router.GET("/currencies", server.GetCurrencies)
+	router.GET("/exchange", server.ExchangeCurrencies)
+	router.GET("/convert", server.ConvertCurrencies)
+	router.POST("/convert/batch", server.ConvertBatch)
+	router.GET("/timeseries", server.GetTimeSeries)
+	router.POST("/quotes", server.CreateQuote)
+	router.POST("/quotes/:id/execute", server.ExecuteQuote)
+	router.GET("/nbp/bid-ask", server.GetNBPBidAskRates)
+	router.GET("/nbp/tax-rate", server.GetNBPTaxRate)
 	return router
 }
 
@@ -29,23 +140,23 @@ func TestRatesEndpoint(t *testing.T) {
 	router := setupRouter()
 
 	cases := []struct {
-		name              string
-		url               string
-		wantStatus        int
-		wantRates         []types.ConvertedRate
-		wantEmptyResponse bool
+		name          string
+		url           string
+		wantStatus    int
+		wantRates     []types.ConvertedRate
+		wantErrorCode string
 	}{
 		{
 			name:       "three currencies",
 			url:        "/rates?currencies=USD,GBP,EUR",
 			wantStatus: 200,
 			wantRates: []types.ConvertedRate{
-				{From: "USD", To: "GBP", Rate: decimal.RequireFromString("0.743283")},
-				{From: "GBP", To: "USD", Rate: decimal.RequireFromString("1.3453825797172813")},
-				{From: "USD", To: "EUR", Rate: decimal.RequireFromString("0.861355")},
+				{From: "EUR", To: "GBP", Rate: decimal.RequireFromString("0.8629229527895003")},
 				{From: "EUR", To: "USD", Rate: decimal.RequireFromString("1.1609615083211916")},
 				{From: "GBP", To: "EUR", Rate: decimal.RequireFromString("1.1588520119523788")},
-				{From: "EUR", To: "GBP", Rate: decimal.RequireFromString("0.8629229527895003")},
+				{From: "GBP", To: "USD", Rate: decimal.RequireFromString("1.3453825797172813")},
+				{From: "USD", To: "EUR", Rate: decimal.RequireFromString("0.861355")},
+				{From: "USD", To: "GBP", Rate: decimal.RequireFromString("0.743283")},
 			},
 		},
 		{
@@ -53,28 +164,44 @@ func TestRatesEndpoint(t *testing.T) {
 			url:        "/rates?currencies=GBP,EUR",
 			wantStatus: 200,
 			wantRates: []types.ConvertedRate{
-				{From: "GBP", To: "EUR", Rate: decimal.RequireFromString("1.1588520119523788")},
 				{From: "EUR", To: "GBP", Rate: decimal.RequireFromString("0.8629229527895003")},
+				{From: "GBP", To: "EUR", Rate: decimal.RequireFromString("1.1588520119523788")},
 			},
 		},
 		{
-			name:              "one currency",
-			url:               "/rates?currencies=GBP",
-			wantStatus:        400,
-			wantRates:         []types.ConvertedRate{},
-			wantEmptyResponse: true,
+			name:          "one currency",
+			url:           "/rates?currencies=GBP",
+			wantStatus:    400,
+			wantRates:     []types.ConvertedRate{},
+			wantErrorCode: CodeInvalidRequest,
+		},
+		{
+			name:          "unknown currency",
+			url:           "/rates?currencies=USD,XYZ",
+			wantStatus:    404,
+			wantRates:     []types.ConvertedRate{},
+			wantErrorCode: CodeUnknownCurrency,
+		},
+		{
+			name:       "order does not depend on request order",
+			url:        "/rates?currencies=eur,gbp",
+			wantStatus: 200,
+			wantRates: []types.ConvertedRate{
+				{From: "EUR", To: "GBP", Rate: decimal.RequireFromString("0.8629229527895003")},
+				{From: "GBP", To: "EUR", Rate: decimal.RequireFromString("1.1588520119523788")},
+			},
 		},
 		{
 			name:       "test duplicates",
 			url:        "/rates?currencies=USD,GBP,EUR,GBP",
 			wantStatus: 200,
 			wantRates: []types.ConvertedRate{
-				{From: "USD", To: "GBP", Rate: decimal.RequireFromString("0.743283")},
-				{From: "GBP", To: "USD", Rate: decimal.RequireFromString("1.3453825797172813")},
-				{From: "USD", To: "EUR", Rate: decimal.RequireFromString("0.861355")},
+				{From: "EUR", To: "GBP", Rate: decimal.RequireFromString("0.8629229527895003")},
 				{From: "EUR", To: "USD", Rate: decimal.RequireFromString("1.1609615083211916")},
 				{From: "GBP", To: "EUR", Rate: decimal.RequireFromString("1.1588520119523788")},
-				{From: "EUR", To: "GBP", Rate: decimal.RequireFromString("0.8629229527895003")},
+				{From: "GBP", To: "USD", Rate: decimal.RequireFromString("1.3453825797172813")},
+				{From: "USD", To: "EUR", Rate: decimal.RequireFromString("0.861355")},
+				{From: "USD", To: "GBP", Rate: decimal.RequireFromString("0.743283")},
 			},
 		},
 	}
@@ -91,14 +218,15 @@ func TestRatesEndpoint(t *testing.T) {
 				t.Fatalf("response status=%d, want %d", w.Code, tc.wantStatus)
 			}
 
-			if tc.wantEmptyResponse {
-				var m gin.H
-				if err := json.Unmarshal(w.Body.Bytes(), &m); err != nil {
-					t.Fatalf("expected valid JSON error: %v", err)
-				}
+			if tc.wantErrorCode != "" {
+				assertErrorCode(t, w, tc.wantErrorCode)
 				return
 			}
 
+			if source := w.Header().Get("X-Rates-Source"); source != exchangeratesprovider.MockSource {
+				t.Fatalf("X-Rates-Source=%q, want %q", source, exchangeratesprovider.MockSource)
+			}
+
 			var got []types.ConvertedRate
 			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
 				t.Fatalf("cannot unmarshal: %v", err)
@@ -108,58 +236,76 @@ func TestRatesEndpoint(t *testing.T) {
 				t.Fatalf("unexpected number of rates: got=%d, want=%d", len(got), len(tc.wantRates))
 			}
 
-			sortRates(got)
-			sortRates(tc.wantRates)
-
 			if diff := cmp.Diff(tc.wantRates, got); diff != "" {
 				t.Errorf("%s test mismatch (-want +got):\n%s", tc.name, diff)
 			}
 		})
 	}
 }
-func TestExchangeEndpoint(t *testing.T) {
+func TestRatesMatrixEndpoint(t *testing.T) {
 	router := setupRouter()
 
+	var tooManyCurrencies []string
+	for i := range maxMatrixCurrencies + 1 {
+		tooManyCurrencies = append(tooManyCurrencies, fmt.Sprintf("C%02d", i))
+	}
+
+	d := decimal.RequireFromString
 	cases := []struct {
-		name              string
-		url               string
-		wantStatus        int
-		wantResponse      types.ExchangedCryptoCurrency
-		wantEmptyResponse bool
+		name          string
+		url           string
+		wantStatus    int
+		wantMatrix    types.RatesMatrix
+		wantErrorCode string
 	}{
 		{
-			name:       "WBTC to USDT",
-			url:        "/exchange?from=WBTC&to=USDT&amount=1.0",
+			name:       "matrix keeps request order",
+			url:        "/rates/matrix?currencies=usd,EUR,USD",
 			wantStatus: 200,
-			wantResponse: types.ExchangedCryptoCurrency{
-				From:   "WBTC",
-				To:     "USDT",
-				Amount: decimal.RequireFromString("57094.314314"),
+			wantMatrix: types.RatesMatrix{
+				Source:    exchangeratesprovider.MockSource,
+				Timestamp: exchangeratesprovider.MockRatesTimestamp,
+				Rows:      []string{"USD", "EUR"},
+				Columns:   []string{"USD", "EUR"},
+				Values: [][]decimal.Decimal{
+					{d("1"), d("0.861355")},
+					{d("1.1609615083211916"), d("1")},
+				},
 			},
 		},
 		{
-			name:       "USDT to BEER",
-			url:        "/exchange?from=USDT&to=BEER&amount=1.0",
+			name:       "base row",
+			url:        "/rates/matrix?currencies=USD,EUR&base=gbp",
 			wantStatus: 200,
-			wantResponse: types.ExchangedCryptoCurrency{
-				From:   "USDT",
-				To:     "BEER",
-				Amount: decimal.RequireFromString("40593.2547744819179195"),
+			wantMatrix: types.RatesMatrix{
+				Source:    exchangeratesprovider.MockSource,
+				Timestamp: exchangeratesprovider.MockRatesTimestamp,
+				Rows:      []string{"USD", "EUR"},
+				Columns:   []string{"USD", "EUR"},
+				Values: [][]decimal.Decimal{
+					{d("1"), d("0.861355")},
+					{d("1.1609615083211916"), d("1")},
+				},
+				Base: &types.RatesMatrixRow{Currency: "GBP", Values: []decimal.Decimal{d("1.3453825797172813"), d("1.1588520119523788")}},
 			},
 		},
 		{
-			name:              "MATIC to GATE",
-			url:               "/exchange?from=MATIC&to=GATE&amount=0.999",
-			wantStatus:        400,
-			wantResponse:      types.ExchangedCryptoCurrency{},
-			wantEmptyResponse: true,
+			name:          "unknown base",
+			url:           "/rates/matrix?currencies=USD,EUR&base=XYZ",
+			wantStatus:    404,
+			wantErrorCode: CodeUnknownCurrency,
 		},
 		{
-			name:              "USDT to GATE, no amount",
-			url:               "/exchange?from=USDT&to=GATE",
-			wantStatus:        400,
-			wantResponse:      types.ExchangedCryptoCurrency{},
-			wantEmptyResponse: true,
+			name:          "one currency",
+			url:           "/rates/matrix?currencies=USD",
+			wantStatus:    400,
+			wantErrorCode: CodeInvalidRequest,
+		},
+		{
+			name:          "too many currencies",
+			url:           "/rates/matrix?currencies=" + strings.Join(tooManyCurrencies, ","),
+			wantStatus:    400,
+			wantErrorCode: CodeInvalidRequest,
 		},
 	}
 	for _, tc := range cases {
@@ -167,7 +313,7 @@ func TestExchangeEndpoint(t *testing.T) {
 			w := httptest.NewRecorder()
 			req, err := http.NewRequest("GET", tc.url, nil)
 			if err != nil {
-				t.Fatalf("TestExchangeEndpoint error: %v", err)
+				t.Fatalf("TestRatesMatrixEndpoint error: %v", err)
 			}
 
 			router.ServeHTTP(w, req)
@@ -175,31 +321,1421 @@ func TestExchangeEndpoint(t *testing.T) {
 				t.Fatalf("response status=%d, want %d", w.Code, tc.wantStatus)
 			}
 
-			if tc.wantEmptyResponse {
-				var m gin.H
-				if err := json.Unmarshal(w.Body.Bytes(), &m); err != nil {
-					t.Fatalf("expected valid JSON error: %v", err)
-				}
+			if tc.wantErrorCode != "" {
+				assertErrorCode(t, w, tc.wantErrorCode)
 				return
 			}
 
-			var got types.ExchangedCryptoCurrency
+			var got types.RatesMatrix
 			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
 				t.Fatalf("cannot unmarshal: %v", err)
 			}
 
-			if diff := cmp.Diff(tc.wantResponse, got); diff != "" {
+			if diff := cmp.Diff(tc.wantMatrix, got); diff != "" {
+				t.Errorf("%s test mismatch (-want +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestLatestEndpoint(t *testing.T) {
+	router := setupRouter()
+
+	d := decimal.RequireFromString
+	cases := []struct {
+		name          string
+		url           string
+		wantStatus    int
+		wantLatest    types.LatestRates
+		wantErrorCode string
+	}{
+		{
+			name:       "default base returns every currency",
+			url:        "/latest",
+			wantStatus: 200,
+			wantLatest: types.LatestRates{
+				Timestamp: exchangeratesprovider.MockRatesTimestamp.Unix(),
+				Base:      "USD",
+				Rates: map[string]decimal.Decimal{
+					"EUR": d("0.861355"), "GBP": d("0.743283"), "JPY": d("147.1935"),
+					"KWD": d("0.305721"), "PLN": d("3.6201"), "USD": d("1"),
+				},
+			},
+		},
+		{
+			name:       "rebased and filtered",
+			url:        "/latest?base=eur&symbols=USD,gbp,USD",
+			wantStatus: 200,
+			wantLatest: types.LatestRates{
+				Timestamp: exchangeratesprovider.MockRatesTimestamp.Unix(),
+				Base:      "EUR",
+				Rates:     map[string]decimal.Decimal{"USD": d("1.1609615083211916"), "GBP": d("0.8629229527895003")},
+			},
+		},
+		{
+			name:          "unknown base",
+			url:           "/latest?base=XYZ",
+			wantStatus:    404,
+			wantErrorCode: CodeUnknownCurrency,
+		},
+		{
+			name:          "unknown symbol",
+			url:           "/latest?symbols=EUR,XYZ",
+			wantStatus:    404,
+			wantErrorCode: CodeUnknownCurrency,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", tc.url, nil)
+			if err != nil {
+				t.Fatalf("TestLatestEndpoint error: %v", err)
+			}
+
+			router.ServeHTTP(w, req)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("response status=%d, want %d", w.Code, tc.wantStatus)
+			}
+
+			if tc.wantErrorCode != "" {
+				assertErrorCode(t, w, tc.wantErrorCode)
+				return
+			}
+
+			var raw struct {
+				Rates map[string]json.RawMessage `json:"rates"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &raw); err != nil {
+				t.Fatalf("cannot unmarshal: %v", err)
+			}
+			for currency, rate := range raw.Rates {
+				if strings.HasPrefix(string(rate), `"`) {
+					t.Fatalf("rate of %s=%s, want a JSON number", currency, rate)
+				}
+			}
+
+			var got types.LatestRates
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("cannot unmarshal: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.wantLatest, got); diff != "" {
 				t.Errorf("%s test mismatch (-want +got):\n%s", tc.name, diff)
 			}
 		})
 	}
 }
 
-func sortRates(rates []types.ConvertedRate) {
-	sort.Slice(rates, func(i, j int) bool {
-		if rates[i].From == rates[j].From {
-			return rates[i].To < rates[j].To
+func TestCurrenciesEndpoint(t *testing.T) {
+	router := setupRouter()
+
+	intPtr := func(v int) *int { return &v }
+	cases := []struct {
+		name          string
+		url           string
+		wantStatus    int
+		wantCodes     []string
+		wantInfos     []types.CurrencyInfo
+		wantErrorCode string
+	}{
+		{
+			name:       "every asset, fiat first",
+			url:        "/currencies",
+			wantStatus: 200,
+			wantCodes:  []string{"EUR", "GBP", "JPY", "KWD", "PLN", "USD", "BEER", "FLOKI", "GATE", "USDT", "WBTC"},
+			wantInfos: []types.CurrencyInfo{
+				{Code: "JPY", Type: types.AssetTypeFiat, Name: "Yen", NumericCode: "392", MinorUnits: intPtr(0), Symbol: "¥"},
+				{Code: "WBTC", Type: types.AssetTypeCrypto, Name: "Wrapped Bitcoin", DecimalPlaces: intPtr(8)},
+			},
+		},
+		{
+			name:       "crypto only",
+			url:        "/currencies?type=crypto",
+			wantStatus: 200,
+			wantCodes:  []string{"BEER", "FLOKI", "GATE", "USDT", "WBTC"},
+			wantInfos: []types.CurrencyInfo{
+				{Code: "USDT", Type: types.AssetTypeCrypto, Name: "Tether", DecimalPlaces: intPtr(6)},
+			},
+		},
+		{
+			name:       "fiat only",
+			url:        "/currencies?type=FIAT",
+			wantStatus: 200,
+			wantCodes:  []string{"EUR", "GBP", "JPY", "KWD", "PLN", "USD"},
+			wantInfos: []types.CurrencyInfo{
+				{Code: "KWD", Type: types.AssetTypeFiat, Name: "Kuwaiti Dinar", NumericCode: "414", MinorUnits: intPtr(3), Symbol: "د.ك"},
+			},
+		},
+		{
+			name:          "unknown type",
+			url:           "/currencies?type=stocks",
+			wantStatus:    400,
+			wantErrorCode: CodeInvalidRequest,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", tc.url, nil)
+			if err != nil {
+				t.Fatalf("TestCurrenciesEndpoint error: %v", err)
+			}
+
+			router.ServeHTTP(w, req)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("response status=%d, want %d", w.Code, tc.wantStatus)
+			}
+
+			if tc.wantErrorCode != "" {
+				assertErrorCode(t, w, tc.wantErrorCode)
+				return
+			}
+
+			var got []types.CurrencyInfo
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("cannot unmarshal: %v", err)
+			}
+
+			var codes []string
+			byCode := map[string]types.CurrencyInfo{}
+			for _, info := range got {
+				codes = append(codes, info.Code)
+				byCode[info.Code] = info
+			}
+			if diff := cmp.Diff(tc.wantCodes, codes); diff != "" {
+				t.Errorf("codes mismatch (-want +got):\n%s", diff)
+			}
+			for _, want := range tc.wantInfos {
+				if diff := cmp.Diff(want, byCode[want.Code]); diff != "" {
+					t.Errorf("%s mismatch (-want +got):\n%s", want.Code, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	server := newTestServer(types.Readiness{}, nil)
+
+	for _, url := range []string{"/rates?currencies=USD,EUR", "/rates?currencies=USD", "/metrics"} {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatalf("TestMetricsEndpoint error: %v", err)
+		}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if url != "/metrics" {
+			continue
+		}
+
+		if w.Code != 200 {
+			t.Fatalf("response status=%d, want 200", w.Code)
+		}
+		body := w.Body.String()
+		for _, want := range []string{
+			`currency_converter_http_requests_total{method="GET",route="/rates",status="200"} 1`,
+			`currency_converter_http_requests_total{method="GET",route="/rates",status="400"} 1`,
+			`currency_converter_http_request_duration_seconds_bucket{method="GET",route="/rates",status="200",le="+Inf"} 1`,
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("metrics output does not contain %q", want)
+			}
 		}
-		return rates[i].From < rates[j].From
+	}
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	ready := types.Readiness{Ready: true, Checks: map[string]types.DependencyStatus{
+		"fiat_rates": {Status: "ok"},
+		"store":      {Status: "disabled"},
+	}}
+	notReady := types.Readiness{Ready: false, Checks: map[string]types.DependencyStatus{
+		"fiat_rates": {Status: "not_loaded", Error: "no rates snapshot loaded yet"},
+		"store":      {Status: "disabled"},
+	}}
+
+	cases := []struct {
+		name       string
+		url        string
+		readiness  types.Readiness
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "liveness does not depend on readiness",
+			url:        "/healthz",
+			readiness:  notReady,
+			wantStatus: 200,
+			wantBody:   `{"status":"ok"}`,
+		},
+		{
+			name:       "ready",
+			url:        "/readyz",
+			readiness:  ready,
+			wantStatus: 200,
+			wantBody:   `{"ready":true,"checks":{"fiat_rates":{"status":"ok"},"store":{"status":"disabled"}}}`,
+		},
+		{
+			name:       "not ready",
+			url:        "/readyz",
+			readiness:  notReady,
+			wantStatus: 503,
+			wantBody:   `{"ready":false,"checks":{"fiat_rates":{"status":"not_loaded","error":"no rates snapshot loaded yet"},"store":{"status":"disabled"}}}`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(tc.readiness, nil)
+
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", tc.url, nil)
+			if err != nil {
+				t.Fatalf("TestHealthEndpoints error: %v", err)
+			}
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != tc.wantStatus {
+				t.Fatalf("response status=%d, want %d", w.Code, tc.wantStatus)
+			}
+			if got := w.Body.String(); got != tc.wantBody {
+				t.Errorf("body=%s, want %s", got, tc.wantBody)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	keys, err := auth.NewFileKeyStore(types.APIKey{
+		ID:            "partner",
+		Hash:          auth.HashKey("secret"),
+		RatePerSecond: 0.001,
+		Burst:         2,
+		DailyQuota:    100,
 	})
+	if err != nil {
+		t.Fatalf("NewFileKeyStore error: %v", err)
+	}
+	server := newTestServer(types.Readiness{Ready: true}, auth.NewAuthenticator(keys))
+
+	cases := []struct {
+		name           string
+		url            string
+		key            string
+		wantStatus     int
+		wantErrorCode  string
+		wantRemaining  string
+		wantRetryAfter bool
+	}{
+		{name: "missing key", url: "/rates?currencies=USD,EUR", wantStatus: 401, wantErrorCode: CodeUnauthorized},
+		{name: "invalid key", url: "/rates?currencies=USD,EUR", key: "guess", wantStatus: 401, wantErrorCode: CodeUnauthorized},
+		{name: "first request", url: "/rates?currencies=USD,EUR", key: "secret", wantStatus: 200, wantRemaining: "99"},
+		{name: "second request", url: "/rates?currencies=USD,EUR", key: "secret", wantStatus: 200, wantRemaining: "98"},
+		{name: "burst exhausted", url: "/rates?currencies=USD,EUR", key: "secret", wantStatus: 429, wantErrorCode: CodeRateLimited, wantRemaining: "98", wantRetryAfter: true},
+		{name: "health is open", url: "/healthz", wantStatus: 200},
+		{name: "metrics are open", url: "/metrics", wantStatus: 200},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", tc.url, nil)
+			if err != nil {
+				t.Fatalf("TestAPIKeyAuth error: %v", err)
+			}
+			if tc.key != "" {
+				req.Header.Set("X-API-Key", tc.key)
+			}
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != tc.wantStatus {
+				t.Fatalf("response status=%d, want %d", w.Code, tc.wantStatus)
+			}
+			if tc.wantErrorCode != "" {
+				assertErrorCode(t, w, tc.wantErrorCode)
+			}
+			if got := w.Header().Get("X-RateLimit-Remaining"); got != tc.wantRemaining {
+				t.Errorf("X-RateLimit-Remaining=%q, want %q", got, tc.wantRemaining)
+			}
+			if tc.wantRemaining != "" && w.Header().Get("X-RateLimit-Limit") != "100" {
+				t.Errorf("X-RateLimit-Limit=%q, want 100", w.Header().Get("X-RateLimit-Limit"))
+			}
+			if got := w.Header().Get("Retry-After") != ""; got != tc.wantRetryAfter {
+				t.Errorf("Retry-After set=%t, want %t", got, tc.wantRetryAfter)
+			}
+		})
+	}
+}
+
+// TestOpenAPIConformance checks real responses of every documented operation
+// against openapi.json, and that every registered route is documented.
+func TestOpenAPIConformance(t *testing.T) {
+	ctx := context.Background()
+	doc, err := openapi3.NewLoader().LoadFromData(openAPISpec)
+	if err != nil {
+		t.Fatalf("cannot load openapi.json: %v", err)
+	}
+	if err := doc.Validate(ctx); err != nil {
+		t.Fatalf("openapi.json is not a valid OpenAPI 3 document: %v", err)
+	}
+	// kin-openapi has no decoder for the Swagger UI files and the rates stream
+	for _, contentType := range []string{"text/html", "text/css", "text/javascript", "text/event-stream"} {
+		openapi3filter.RegisterBodyDecoder(contentType, openapi3filter.FileBodyDecoder)
+		defer openapi3filter.UnregisterBodyDecoder(contentType)
+	}
+	specRouter, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		t.Fatalf("cannot build router from openapi.json: %v", err)
+	}
+
+	keys, err := auth.NewFileKeyStore(types.APIKey{ID: "conformance", Hash: auth.HashKey("secret"), RatePerSecond: 1000, Burst: 1000, DailyQuota: 1000})
+	if err != nil {
+		t.Fatalf("NewFileKeyStore error: %v", err)
+	}
+	server := newTestServer(types.Readiness{Ready: true, Checks: map[string]types.DependencyStatus{"store": {Status: "disabled"}}}, auth.NewAuthenticator(keys))
+
+	serve := func(method, url, body string, withKey bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if withKey {
+			req.Header.Set("X-API-Key", "secret")
+		}
+		if strings.HasPrefix(url, "/v1/stream/") {
+			// streams end only when the client disconnects
+			ctx, cancel := context.WithTimeout(req.Context(), 50*time.Millisecond)
+			defer cancel()
+			req = req.WithContext(ctx)
+		}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	created := serve("POST", "/v1/quotes", `{"from": "EUR", "to": "PLN", "amount": "100"}`, true)
+	var quote types.Quote
+	if err := json.Unmarshal(created.Body.Bytes(), &quote); err != nil {
+		t.Fatalf("cannot create quote: %v, body: %s", err, created.Body.String())
+	}
+
+	cases := []struct {
+		method     string
+		url        string
+		body       string
+		withoutKey bool
+		wantStatus int
+	}{
+		{method: "GET", url: "/v1/rates?currencies=USD,EUR,PLN", wantStatus: 200},
+		{method: "GET", url: "/v1/rates?currencies=USD", wantStatus: 400},
+		{method: "GET", url: "/v1/rates?currencies=USD,XYZ", wantStatus: 404},
+		{method: "GET", url: "/v1/rates?currencies=USD,EUR", withoutKey: true, wantStatus: 401},
+		{method: "GET", url: "/v1/rates/historical?date=2025-06-30&currencies=USD,PLN", wantStatus: 200},
+		{method: "GET", url: "/v1/rates/matrix?currencies=USD,EUR&base=PLN", wantStatus: 200},
+		{method: "GET", url: "/v1/stream/rates?currencies=USD,EUR,PLN", wantStatus: 200},
+		{method: "GET", url: "/v1/stream/rates?currencies=USD,XYZ", wantStatus: 404},
+		{method: "GET", url: "/v1/latest?base=EUR&symbols=USD,PLN", wantStatus: 200},
+		{method: "GET", url: "/v1/currencies", wantStatus: 200},
+		{method: "GET", url: "/v1/currencies?type=stocks", wantStatus: 400},
+		{method: "GET", url: "/v1/timeseries?from=USD&to=PLN&start=2025-01-01&end=2025-01-20&interval=week", wantStatus: 200},
+		{method: "GET", url: "/v1/exchange?from=USDT&to=WBTC&amount=1.5", wantStatus: 200},
+		{method: "GET", url: "/v1/convert?from=EUR&to=PLN&amount=100", wantStatus: 200},
+		{method: "POST", url: "/v1/convert/batch", body: `[{"id": "1", "from": "EUR", "to": "PLN", "amount": "100"}, {"id": "2", "from": "EUR", "to": "XYZ", "amount": "1"}]`, wantStatus: 200},
+		{method: "POST", url: "/v1/quotes", body: `{"from": "USD", "to": "WBTC", "amount": "1000"}`, wantStatus: 201},
+		{method: "POST", url: "/v1/quotes/" + quote.ID + "/execute", wantStatus: 200},
+		{method: "POST", url: "/v1/quotes/missing/execute", wantStatus: 404},
+		{method: "GET", url: "/v1/nbp/bid-ask", wantStatus: 200},
+		{method: "GET", url: "/v1/nbp/bid-ask?date=2025-06-29", wantStatus: 200},
+		{method: "GET", url: "/v1/nbp/tax-rate?currency=EUR&date=2025-06-30", wantStatus: 200},
+		{method: "GET", url: "/v1/nbp/tax-rate?currency=XYZ&date=2025-06-30", wantStatus: 404},
+		{method: "GET", url: "/healthz", withoutKey: true, wantStatus: 200},
+		{method: "GET", url: "/readyz", withoutKey: true, wantStatus: 200},
+		{method: "GET", url: "/metrics", withoutKey: true, wantStatus: 200},
+		{method: "GET", url: "/openapi.json", withoutKey: true, wantStatus: 200},
+		{method: "GET", url: "/docs", withoutKey: true, wantStatus: 200},
+		{method: "GET", url: "/docs/swagger-ui.css", withoutKey: true, wantStatus: 200},
+		{method: "GET", url: "/docs/swagger-ui-bundle.js", withoutKey: true, wantStatus: 200},
+	}
+	covered := map[string]bool{}
+	for _, tc := range cases {
+		t.Run(tc.method+" "+tc.url, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			route, pathParams, err := specRouter.FindRoute(req)
+			if err != nil {
+				t.Fatalf("operation not documented: %v", err)
+			}
+			covered[route.Operation.OperationID] = true
+			input := &openapi3filter.RequestValidationInput{
+				Request:    req,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+			}
+			if err := openapi3filter.ValidateRequest(ctx, input); err != nil {
+				t.Fatalf("request does not match the spec: %v", err)
+			}
+
+			w := serve(tc.method, tc.url, tc.body, !tc.withoutKey)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("response status=%d, want %d, body: %s", w.Code, tc.wantStatus, w.Body.String())
+			}
+			err = openapi3filter.ValidateResponse(ctx, &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: input,
+				Status:                 w.Code,
+				Header:                 w.Header(),
+				Body:                   io.NopCloser(bytes.NewReader(w.Body.Bytes())),
+				Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+			})
+			if err != nil {
+				t.Errorf("response does not match the spec: %v", err)
+			}
+		})
+	}
+
+	for path, item := range doc.Paths.Map() {
+		for _, operation := range item.Operations() {
+			if !covered[operation.OperationID] {
+				t.Errorf("operation %s (%s) is not covered by the conformance test", operation.OperationID, path)
+			}
+		}
+	}
+	for _, route := range server.router.Routes() {
+		path := route.Path
+		for _, segment := range strings.Split(route.Path, "/") {
+			if strings.HasPrefix(segment, ":") {
+				path = strings.Replace(path, segment, "{"+segment[1:]+"}", 1)
+			}
+		}
+		item := doc.Paths.Find(path)
+		if item == nil {
+			// deprecated root aliases are documented by their /v1 route
+			item = doc.Paths.Find("/v1" + path)
+		}
+		if item == nil || item.GetOperation(route.Method) == nil {
+			t.Errorf("route %s %s is not documented in openapi.json", route.Method, route.Path)
+		}
+	}
+}
+
+func TestDocsServeVendoredSwaggerUI(t *testing.T) {
+	server := newTestServer(types.Readiness{Ready: true}, nil)
+	serve := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w
+	}
+
+	page := serve("/docs").Body.String()
+	if strings.Contains(page, "://") {
+		t.Fatalf("docs page loads assets from another host:\n%s", page)
+	}
+	for _, asset := range []struct {
+		url         string
+		contentType string
+	}{
+		{"/docs/swagger-ui.css", "text/css"},
+		{"/docs/swagger-ui-bundle.js", "text/javascript"},
+	} {
+		if !strings.Contains(page, `"`+asset.url+`"`) {
+			t.Errorf("docs page does not reference %s", asset.url)
+		}
+		w := serve(asset.url)
+		if w.Code != http.StatusOK || w.Body.Len() == 0 {
+			t.Errorf("GET %s status=%d, body length=%d, want 200 with a body", asset.url, w.Code, w.Body.Len())
+		}
+		if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, asset.contentType) {
+			t.Errorf("GET %s Content-Type=%s, want %s", asset.url, got, asset.contentType)
+		}
+	}
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	server := newTestServer(types.Readiness{Ready: true}, nil)
+
+	cases := []struct {
+		name           string
+		method         string
+		url            string
+		wantStatus     int
+		wantDeprecated bool
+		wantLink       string
+	}{
+		{name: "v1 route", method: "GET", url: "/v1/rates?currencies=USD,EUR", wantStatus: 200},
+		{name: "root alias", method: "GET", url: "/rates?currencies=USD,EUR", wantStatus: 200, wantDeprecated: true, wantLink: `</v1/rates>; rel="successor-version"`},
+		{name: "root alias error", method: "GET", url: "/rates?currencies=USD", wantStatus: 400, wantDeprecated: true, wantLink: `</v1/rates>; rel="successor-version"`},
+		{name: "root alias with path parameter", method: "POST", url: "/quotes/abc/execute", wantStatus: 404, wantDeprecated: true, wantLink: `</v1/quotes/abc/execute>; rel="successor-version"`},
+		{name: "v1 route with path parameter", method: "POST", url: "/v1/quotes/abc/execute", wantStatus: 404},
+		{name: "unversioned operational route", method: "GET", url: "/healthz", wantStatus: 200},
+		{name: "unknown version", method: "GET", url: "/v2/rates?currencies=USD,EUR", wantStatus: 404},
+	}
+	serve := func(method, url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+		return w
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := serve(tc.method, tc.url)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("response status=%d, want %d", w.Code, tc.wantStatus)
+			}
+			wantDeprecation, wantSunset := "", ""
+			if tc.wantDeprecated {
+				wantDeprecation, wantSunset = "@1792281600", "Fri, 30 Apr 2027 00:00:00 GMT"
+			}
+			if got := w.Header().Get("Deprecation"); got != wantDeprecation {
+				t.Errorf("Deprecation=%q, want %q", got, wantDeprecation)
+			}
+			if got := w.Header().Get("Sunset"); got != wantSunset {
+				t.Errorf("Sunset=%q, want %q", got, wantSunset)
+			}
+			if got := w.Header().Get("Link"); got != tc.wantLink {
+				t.Errorf("Link=%q, want %q", got, tc.wantLink)
+			}
+		})
+	}
+
+	v1, alias := serve("GET", "/v1/rates?currencies=USD,EUR"), serve("GET", "/rates?currencies=USD,EUR")
+	if v1.Body.String() != alias.Body.String() {
+		t.Errorf("root alias body=%s, want the v1 body %s", alias.Body.String(), v1.Body.String())
+	}
+}
+
+// changingRatesProvider serves the mock rates with PLN replaced by the last set rate.
+type changingRatesProvider struct {
+	*exchangeratesprovider.ExchangeRatesProviderMock
+	mu  sync.Mutex
+	pln decimal.Decimal
+}
+
+func (p *changingRatesProvider) GetExchangeRates(ctx context.Context) (types.ExchangeRates, error) {
+	rates, err := p.ExchangeRatesProviderMock.GetExchangeRates(ctx)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rates.Rates["PLN"] = p.pln
+	return rates, err
+}
+
+func (p *changingRatesProvider) setPLN(rate string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pln = decimal.RequireFromString(rate)
+}
+
+type streamEvent struct {
+	id, event, data string
+}
+
+func readStreamEvent(t *testing.T, reader *bufio.Reader) streamEvent {
+	t.Helper()
+	var event streamEvent
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("cannot read stream event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return event
+		}
+		field, value, _ := strings.Cut(line, ":")
+		switch field {
+		case "id":
+			event.id = value
+		case "event":
+			event.event = value
+		case "data":
+			event.data = value
+		}
+	}
+}
+
+func TestStreamRatesEndpoint(t *testing.T) {
+	defer func(interval time.Duration) { streamHeartbeatInterval = interval }(streamHeartbeatInterval)
+	streamHeartbeatInterval = 50 * time.Millisecond
+
+	provider := &changingRatesProvider{ExchangeRatesProviderMock: exchangeratesprovider.NewExchangeRatesProviderMock()}
+	provider.setPLN("3.6201")
+	updates := newFakeUpdates()
+	hub := ratesstream.NewHub(updates)
+	converter := currencyconverter.NewConverter(provider, provider, fees.Schedule{})
+	server := NewGinServer("8080", converter, nil, metrics.New(), stubReadinessChecker{}, nil, hub, nil)
+	server.RegisterRoutes()
+	httpServer := httptest.NewServer(server.router)
+	t.Cleanup(httpServer.Close)
+
+	open := func(lastEventID string) (*http.Response, *bufio.Reader) {
+		t.Helper()
+		req, err := http.NewRequest("GET", httpServer.URL+"/v1/stream/rates?currencies=usd,PLN", nil)
+		if err != nil {
+			t.Fatalf("cannot build request: %v", err)
+		}
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("cannot open stream: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+			t.Fatalf("status=%d, content type=%q, want 200 event stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		return resp, bufio.NewReader(resp.Body)
+	}
+	wantRates := func(event streamEvent, pln string) {
+		t.Helper()
+		var payload ratesEvent
+		if event.event != "rates" || event.id == "" {
+			t.Fatalf("event=%+v, want rates event with an id", event)
+		}
+		if err := json.Unmarshal([]byte(event.data), &payload); err != nil {
+			t.Fatalf("cannot decode rates event: %v", err)
+		}
+		if payload.Source != exchangeratesprovider.MockSource || len(payload.Rates) != 2 {
+			t.Fatalf("payload=%+v, want both mock pairs", payload)
+		}
+		if rate := payload.Rates[1]; rate.From != "USD" || rate.To != "PLN" || !rate.Rate.Equal(decimal.RequireFromString(pln)) {
+			t.Fatalf("rate=%+v, want USD to PLN at %s", rate, pln)
+		}
+	}
+
+	_, reader := open("")
+	initial := readStreamEvent(t, reader)
+	wantRates(initial, "3.6201")
+
+	provider.setPLN("3.7")
+	updates.signal()
+	changed := readStreamEvent(t, reader)
+	wantRates(changed, "3.7")
+	if changed.id == initial.id {
+		t.Fatalf("id=%s, want a new id after rates changed", changed.id)
+	}
+
+	updates.signal()
+	if event := readStreamEvent(t, reader); event.event != "heartbeat" {
+		t.Fatalf("event=%+v, want heartbeat since rates did not change", event)
+	}
+
+	resumed, resumedReader := open(changed.id)
+	if event := readStreamEvent(t, resumedReader); event.event != "heartbeat" {
+		t.Fatalf("event=%+v, want heartbeat since the client has the current rates", event)
+	}
+	resumed.Body.Close()
+	deadline := time.Now().Add(time.Second)
+	for hub.Len() != 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if hub.Len() != 1 {
+		t.Fatalf("open streams=%d, want 1 after disconnect", hub.Len())
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown error: %v", err)
+	}
+	if _, err := io.ReadAll(reader); err != nil {
+		t.Fatalf("stream did not end cleanly on shutdown: %v", err)
+	}
+	if hub.Len() != 0 {
+		t.Fatalf("open streams=%d, want 0 after shutdown", hub.Len())
+	}
+}
+
+func TestHistoricalRatesEndpoint(t *testing.T) {
+	router := setupRouter()
+
+	cases := []struct {
+		name          string
+		url           string
+		wantStatus    int
+		wantRates     []types.ConvertedRate
+		wantErrorCode string
+	}{
+		{
+			name:       "USD and PLN",
+			url:        "/rates/historical?date=2025-06-30&currencies=USD,PLN",
+			wantStatus: 200,
+			wantRates: []types.ConvertedRate{
+				{From: "PLN", To: "USD", Rate: decimal.RequireFromString("0.2564102564102564")},
+				{From: "USD", To: "PLN", Rate: decimal.RequireFromString("3.9")},
+			},
+		},
+		{
+			name:          "malformed date",
+			url:           "/rates/historical?date=30.06.2025&currencies=USD,PLN",
+			wantStatus:    400,
+			wantErrorCode: CodeInvalidRequest,
+		},
+		{
+			name:          "date before history starts",
+			url:           "/rates/historical?date=1998-12-31&currencies=USD,PLN",
+			wantStatus:    400,
+			wantErrorCode: CodeInvalidRequest,
+		},
+		{
+			name:          "future date",
+			url:           "/rates/historical?date=" + time.Now().AddDate(0, 0, 2).Format(time.DateOnly) + "&currencies=USD,PLN",
+			wantStatus:    400,
+			wantErrorCode: CodeInvalidRequest,
+		},
+		{
+			name:          "missing date",
+			url:           "/rates/historical?currencies=USD,PLN",
+			wantStatus:    400,
+			wantErrorCode: CodeInvalidRequest,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", tc.url, nil)
+			if err != nil {
+				t.Fatalf("TestHistoricalRatesEndpoint error: %v", err)
+			}
+
+			router.ServeHTTP(w, req)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("response status=%d, want %d", w.Code, tc.wantStatus)
+			}
+
+			if tc.wantErrorCode != "" {
+				assertErrorCode(t, w, tc.wantErrorCode)
+				return
+			}
+
+			var got []types.ConvertedRate
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("cannot unmarshal: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.wantRates, got); diff != "" {
+				t.Errorf("%s test mismatch (-want +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestNBPEndpoints(t *testing.T) {
+	router := setupRouter()
+
+	cases := []struct {
+		name          string
+		url           string
+		wantStatus    int
+		wantBody      string
+		wantErrorCode string
+	}{
+		{
+			name:       "latest bid/ask",
+			url:        "/nbp/bid-ask",
+			wantStatus: 200,
+			wantBody:   `{"table":"124/C/NBP/2025","effective_date":"2025-06-30T00:00:00Z","rates":{"EUR":{"bid":"4.1995","ask":"4.2843"}}}`,
+		},
+		{
+			name:       "historical bid/ask",
+			url:        "/nbp/bid-ask?date=2025-06-29",
+			wantStatus: 200,
+			wantBody:   `{"table":"123/C/NBP/2025","effective_date":"2025-06-27T00:00:00Z","rates":{"EUR":{"bid":"4.2019","ask":"4.2867"}}}`,
+		},
+		{
+			name:          "bid/ask with malformed date",
+			url:           "/nbp/bid-ask?date=29.06.2025",
+			wantStatus:    400,
+			wantErrorCode: CodeInvalidRequest,
+		},
+		{
+			name:          "bid/ask before the first nbp table",
+			url:           "/nbp/bid-ask?date=2002-01-01",
+			wantStatus:    400,
+			wantErrorCode: CodeInvalidRequest,
+		},
+		{
+			name:       "tax rate",
+			url:        "/nbp/tax-rate?currency=eur&date=2025-06-30",
+			wantStatus: 200,
+			wantBody:   `{"currency":"EUR","date":"2025-06-30T00:00:00Z","rate":"4.2443","table":"123/A/NBP/2025","effective_date":"2025-06-27T00:00:00Z"}`,
+		},
+		{
+			name:          "tax rate without date",
+			url:           "/nbp/tax-rate?currency=EUR",
+			wantStatus:    400,
+			wantErrorCode: CodeInvalidRequest,
+		},
+		{
+			name:          "tax rate without a table published before date",
+			url:           "/nbp/tax-rate?currency=EUR&date=2002-01-02",
+			wantStatus:    400,
+			wantErrorCode: CodeInvalidRequest,
+		},
+		{
+			name:          "tax rate for unknown currency",
+			url:           "/nbp/tax-rate?currency=XYZ&date=2025-06-30",
+			wantStatus:    404,
+			wantErrorCode: CodeUnknownCurrency,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", tc.url, nil)
+			if err != nil {
+				t.Fatalf("TestNBPEndpoints error: %v", err)
+			}
+
+			router.ServeHTTP(w, req)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("response status=%d, want %d, body: %s", w.Code, tc.wantStatus, w.Body.String())
+			}
+
+			if tc.wantErrorCode != "" {
+				assertErrorCode(t, w, tc.wantErrorCode)
+				return
+			}
+			if w.Body.String() != tc.wantBody {
+				t.Errorf("body=%s, want %s", w.Body.String(), tc.wantBody)
+			}
+		})
+	}
+}
+
+func TestExchangeEndpoint(t *testing.T) {
+	router := setupRouter()
+
+	cases := []struct {
+		name          string
+		url           string
+		wantStatus    int
+		wantResponse  types.ExchangedCurrency
+		wantErrorCode string
+	}{
+		{
+			name:       "WBTC to USDT",
+			url:        "/exchange?from=WBTC&to=USDT&amount=1.0",
+			wantStatus: 200,
+			wantResponse: types.ExchangedCurrency{
+				From:      "WBTC",
+				To:        "USDT",
+				Amount:    decimal.RequireFromString("57094.314314"),
+				Rate:      decimal.RequireFromString("57094.3143143143143143"),
+				MidRate:   decimal.RequireFromString("57094.3143143143143143"),
+				Fee:       decimal.Zero,
+				NetAmount: decimal.RequireFromString("57094.314314"),
+				Path:      []string{"WBTC", "USD", "USDT"},
+				Source:    exchangeratesprovider.MockSource,
+			},
+		},
+		{
+			name:       "USDT to BEER",
+			url:        "/exchange?from=USDT&to=BEER&amount=1.0",
+			wantStatus: 200,
+			wantResponse: types.ExchangedCurrency{
+				From:      "USDT",
+				To:        "BEER",
+				Amount:    decimal.RequireFromString("40593.2547744819179195"),
+				Rate:      decimal.RequireFromString("40593.2547744819179195"),
+				MidRate:   decimal.RequireFromString("40593.2547744819179195"),
+				Fee:       decimal.Zero,
+				NetAmount: decimal.RequireFromString("40593.2547744819179195"),
+				Path:      []string{"USDT", "USD", "BEER"},
+				Source:    exchangeratesprovider.MockSource,
+			},
+		},
+		{
+			name:       "WBTC to EUR rounds to fiat minor units",
+			url:        "/exchange?from=WBTC&to=EUR&amount=0.5",
+			wantStatus: 200,
+			wantResponse: types.ExchangedCurrency{
+				From:      "WBTC",
+				To:        "EUR",
+				Amount:    decimal.RequireFromString("24564.65"),
+				Rate:      decimal.RequireFromString("49129.2946331"),
+				MidRate:   decimal.RequireFromString("49129.2946331"),
+				Fee:       decimal.Zero,
+				NetAmount: decimal.RequireFromString("24564.65"),
+				Path:      []string{"WBTC", "USD", "EUR"},
+				Source:    exchangeratesprovider.MockSource,
+			},
+		},
+		{
+			name:       "EUR to USDT rounds to token decimal places",
+			url:        "/exchange?from=EUR&to=USDT&amount=100",
+			wantStatus: 200,
+			wantResponse: types.ExchangedCurrency{
+				From:      "EUR",
+				To:        "USDT",
+				Amount:    decimal.RequireFromString("116.212363"),
+				Rate:      decimal.RequireFromString("1.1621236319531448"),
+				MidRate:   decimal.RequireFromString("1.1621236319531448"),
+				Fee:       decimal.Zero,
+				NetAmount: decimal.RequireFromString("116.212363"),
+				Path:      []string{"EUR", "USD", "USDT"},
+				Source:    exchangeratesprovider.MockSource,
+			},
+		},
+		{
+			name:       "USD to WBTC skips pivot",
+			url:        "/exchange?from=USD&to=WBTC&amount=1000",
+			wantStatus: 200,
+			wantResponse: types.ExchangedCurrency{
+				From:      "USD",
+				To:        "WBTC",
+				Amount:    decimal.RequireFromString("0.01753241"),
+				Rate:      decimal.RequireFromString("0.0000175324112921"),
+				MidRate:   decimal.RequireFromString("0.0000175324112921"),
+				Fee:       decimal.Zero,
+				NetAmount: decimal.RequireFromString("0.01753241"),
+				Path:      []string{"USD", "WBTC"},
+				Source:    exchangeratesprovider.MockSource,
+			},
+		},
+		{
+			name:          "MATIC to GATE",
+			url:           "/exchange?from=MATIC&to=GATE&amount=0.999",
+			wantStatus:    404,
+			wantResponse:  types.ExchangedCurrency{},
+			wantErrorCode: CodeUnknownCurrency,
+		},
+		{
+			name:          "USDT to GATE, no amount",
+			url:           "/exchange?from=USDT&to=GATE",
+			wantStatus:    400,
+			wantResponse:  types.ExchangedCurrency{},
+			wantErrorCode: CodeInvalidRequest,
+		},
+		{
+			name:          "EUR to PLN, negative amount",
+			url:           "/exchange?from=EUR&to=PLN&amount=-5",
+			wantStatus:    400,
+			wantResponse:  types.ExchangedCurrency{},
+			wantErrorCode: CodeInvalidRequest,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", tc.url, nil)
+			if err != nil {
+				t.Fatalf("TestExchangeEndpoint error: %v", err)
+			}
+
+			router.ServeHTTP(w, req)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("response status=%d, want %d", w.Code, tc.wantStatus)
+			}
+
+			if tc.wantErrorCode != "" {
+				assertErrorCode(t, w, tc.wantErrorCode)
+				return
+			}
+
+			var got types.ExchangedCurrency
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("cannot unmarshal: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.wantResponse, got); diff != "" {
+				t.Errorf("%s test mismatch (-want +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestConvertEndpoint(t *testing.T) {
+	router := setupRouter()
+
+	cases := []struct {
+		name          string
+		url           string
+		wantStatus    int
+		wantResponse  types.ConvertedAmount
+		wantErrorCode string
+	}{
+		{
+			name:       "EUR to GBP",
+			url:        "/convert?from=EUR&to=GBP&amount=125.50",
+			wantStatus: 200,
+			wantResponse: types.ConvertedAmount{
+				From:      "EUR",
+				To:        "GBP",
+				Amount:    decimal.RequireFromString("125.50"),
+				Result:    decimal.RequireFromString("108.30"),
+				Source:    exchangeratesprovider.MockSource,
+				Rate:      decimal.RequireFromString("0.8629229527895003"),
+				MidRate:   decimal.RequireFromString("0.8629229527895003"),
+				Fee:       decimal.Zero,
+				NetAmount: decimal.RequireFromString("108.30"),
+				Timestamp: exchangeratesprovider.MockRatesTimestamp,
+			},
+		},
+		{
+			name:       "EUR to JPY rounds to zero minor units",
+			url:        "/convert?from=eur&to=jpy&amount=125.50",
+			wantStatus: 200,
+			wantResponse: types.ConvertedAmount{
+				From:      "EUR",
+				To:        "JPY",
+				Amount:    decimal.RequireFromString("125.50"),
+				Result:    decimal.RequireFromString("21446"),
+				Source:    exchangeratesprovider.MockSource,
+				Rate:      decimal.RequireFromString("170.8859877750753174"),
+				MidRate:   decimal.RequireFromString("170.8859877750753174"),
+				Fee:       decimal.Zero,
+				NetAmount: decimal.RequireFromString("21446"),
+				Timestamp: exchangeratesprovider.MockRatesTimestamp,
+			},
+		},
+		{
+			name:       "USD to KWD rounds to three minor units",
+			url:        "/convert?from=USD&to=KWD&amount=100",
+			wantStatus: 200,
+			wantResponse: types.ConvertedAmount{
+				From:      "USD",
+				To:        "KWD",
+				Amount:    decimal.RequireFromString("100"),
+				Result:    decimal.RequireFromString("30.572"),
+				Source:    exchangeratesprovider.MockSource,
+				Rate:      decimal.RequireFromString("0.305721"),
+				MidRate:   decimal.RequireFromString("0.305721"),
+				Fee:       decimal.Zero,
+				NetAmount: decimal.RequireFromString("30.572"),
+				Timestamp: exchangeratesprovider.MockRatesTimestamp,
+			},
+		},
+		{
+			name:          "unknown currency",
+			url:           "/convert?from=EUR&to=XYZ&amount=1",
+			wantStatus:    404,
+			wantErrorCode: CodeUnknownCurrency,
+		},
+		{
+			name:          "malformed amount",
+			url:           "/convert?from=EUR&to=GBP&amount=12,5",
+			wantStatus:    400,
+			wantErrorCode: CodeInvalidRequest,
+		},
+		{
+			name:          "zero amount",
+			url:           "/convert?from=EUR&to=GBP&amount=0",
+			wantStatus:    400,
+			wantErrorCode: CodeInvalidRequest,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", tc.url, nil)
+			if err != nil {
+				t.Fatalf("TestConvertEndpoint error: %v", err)
+			}
+
+			router.ServeHTTP(w, req)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("response status=%d, want %d", w.Code, tc.wantStatus)
+			}
+
+			if tc.wantErrorCode != "" {
+				assertErrorCode(t, w, tc.wantErrorCode)
+				return
+			}
+
+			var got types.ConvertedAmount
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("cannot unmarshal: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.wantResponse, got); diff != "" {
+				t.Errorf("%s test mismatch (-want +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestTimeSeriesEndpoint(t *testing.T) {
+	router := setupRouter()
+
+	cases := []struct {
+		name          string
+		url           string
+		wantStatus    int
+		wantPoints    []types.TimeSeriesPoint
+		wantErrorCode string
+	}{
+		{
+			name:       "daily USD to PLN",
+			url:        "/timeseries?from=usd&to=pln&start=2025-01-01&end=2025-01-03",
+			wantStatus: 200,
+			wantPoints: []types.TimeSeriesPoint{
+				{Date: "2025-01-01", Rate: decimal.RequireFromString("3.61")},
+				{Date: "2025-01-02", Rate: decimal.RequireFromString("3.62")},
+				{Date: "2025-01-03", Rate: decimal.RequireFromString("3.63")},
+			},
+		},
+		{
+			name:       "weekly USD to PLN",
+			url:        "/timeseries?from=USD&to=PLN&start=2025-01-01&end=2025-01-20&interval=week",
+			wantStatus: 200,
+			wantPoints: []types.TimeSeriesPoint{
+				{Date: "2025-01-01", Rate: decimal.RequireFromString("3.61")},
+				{Date: "2025-01-08", Rate: decimal.RequireFromString("3.68")},
+				{Date: "2025-01-15", Rate: decimal.RequireFromString("3.75")},
+			},
+		},
+		{
+			name:          "unsupported interval",
+			url:           "/timeseries?from=USD&to=PLN&start=2025-01-01&end=2025-01-03&interval=hour",
+			wantStatus:    400,
+			wantErrorCode: CodeInvalidRequest,
+		},
+		{
+			name:          "range too long",
+			url:           "/timeseries?from=USD&to=PLN&start=2020-01-01&end=2025-01-01",
+			wantStatus:    400,
+			wantErrorCode: CodeInvalidRequest,
+		},
+		{
+			name:          "end before start",
+			url:           "/timeseries?from=USD&to=PLN&start=2025-01-03&end=2025-01-01",
+			wantStatus:    400,
+			wantErrorCode: CodeInvalidRequest,
+		},
+		{
+			name:          "unknown currency",
+			url:           "/timeseries?from=USD&to=XYZ&start=2025-01-01&end=2025-01-03",
+			wantStatus:    404,
+			wantErrorCode: CodeUnknownCurrency,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest("GET", tc.url, nil)
+			if err != nil {
+				t.Fatalf("TestTimeSeriesEndpoint error: %v", err)
+			}
+
+			router.ServeHTTP(w, req)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("response status=%d, want %d", w.Code, tc.wantStatus)
+			}
+
+			if tc.wantErrorCode != "" {
+				assertErrorCode(t, w, tc.wantErrorCode)
+				return
+			}
+
+			var got types.TimeSeries
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("cannot unmarshal: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.wantPoints, got.Points); diff != "" {
+				t.Errorf("%s test mismatch (-want +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestConvertBatchEndpoint(t *testing.T) {
+	router := setupRouter()
+
+	cases := []struct {
+		name          string
+		body          string
+		wantStatus    int
+		wantIDs       []string
+		wantAmounts   map[string]string
+		wantErrors    map[string]string
+		wantErrorCode string
+	}{
+		{
+			name:        "items succeed or fail independently in request order",
+			body:        `[{"id":"1","from":"wbtc","to":"USDT","amount":"1.0"},{"id":"2","from":"EUR","to":"XYZ","amount":"1"},{"id":"3","from":"USD","to":"WBTC","amount":1000},{"id":"4","from":"EUR","amount":"1"},{"id":"5","from":"EUR","to":"PLN"},{"id":"6","from":"EUR","to":"PLN","amount":"-1"}]`,
+			wantStatus:  200,
+			wantIDs:     []string{"1", "2", "3", "4", "5", "6"},
+			wantAmounts: map[string]string{"1": "57094.314314", "3": "0.01753241"},
+			wantErrors:  map[string]string{"2": CodeUnknownCurrency, "4": CodeInvalidRequest, "5": CodeInvalidRequest, "6": CodeInvalidRequest},
+		},
+		{
+			name:          "empty batch",
+			body:          `[]`,
+			wantStatus:    400,
+			wantErrorCode: CodeInvalidRequest,
+		},
+		{
+			name:          "not an array",
+			body:          `{"id":"1","from":"EUR","to":"PLN","amount":"1"}`,
+			wantStatus:    400,
+			wantErrorCode: CodeInvalidRequest,
+		},
+		{
+			name:          "body too large",
+			body:          "[" + strings.Repeat(`{"id":"1","from":"EUR","to":"PLN","amount":"1"},`, 2*maxBatchItems*maxBatchItemBytes/48) + "]",
+			wantStatus:    413,
+			wantErrorCode: CodeRequestTooLarge,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest("POST", "/convert/batch", strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatalf("TestConvertBatchEndpoint error: %v", err)
+			}
+
+			router.ServeHTTP(w, req)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("response status=%d, want %d", w.Code, tc.wantStatus)
+			}
+
+			if tc.wantErrorCode != "" {
+				assertErrorCode(t, w, tc.wantErrorCode)
+				return
+			}
+
+			var got batchConversionResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("cannot unmarshal: %v", err)
+			}
+			if got.Source != exchangeratesprovider.MockSource || !got.Timestamp.Equal(exchangeratesprovider.MockRatesTimestamp) {
+				t.Errorf("snapshot=%s at %s, want %s at %s", got.Source, got.Timestamp, exchangeratesprovider.MockSource, exchangeratesprovider.MockRatesTimestamp)
+			}
+			var ids []string
+			for _, result := range got.Results {
+				ids = append(ids, result.ID)
+				if want, ok := tc.wantAmounts[result.ID]; ok {
+					if result.Result == nil || !result.Result.Amount.Equal(decimal.RequireFromString(want)) {
+						t.Errorf("item %s result=%+v, want amount %s", result.ID, result.Result, want)
+					}
+				}
+				if want, ok := tc.wantErrors[result.ID]; ok {
+					if result.Error == nil || result.Error.Code != want || result.Result != nil {
+						t.Errorf("item %s error=%+v, want only error %s", result.ID, result.Error, want)
+					}
+				}
+			}
+			if diff := cmp.Diff(tc.wantIDs, ids); diff != "" {
+				t.Errorf("result order mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestQuotesEndpoints(t *testing.T) {
+	router := setupRouter()
+
+	create := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/quotes", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("TestQuotesEndpoints error: %v", err)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	execute := func(id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/quotes/"+id+"/execute", nil)
+		if err != nil {
+			t.Fatalf("TestQuotesEndpoints error: %v", err)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := create(`{"from":"wbtc","to":"EUR","amount":"0.5"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status=%d, want %d, body: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+	var quote types.Quote
+	if err := json.Unmarshal(w.Body.Bytes(), &quote); err != nil {
+		t.Fatalf("cannot unmarshal: %v", err)
+	}
+	if quote.ID == "" || quote.From != "WBTC" || !quote.NetAmount.Equal(decimal.RequireFromString("24564.65")) {
+		t.Fatalf("quote=%+v, want WBTC to EUR quote with net amount 24564.65", quote)
+	}
+	if got := quote.ExpiresAt.Sub(quote.CreatedAt); got != quotes.DefaultTTL {
+		t.Fatalf("quote valid for %s, want %s", got, quotes.DefaultTTL)
+	}
+
+	var executions []types.Quote
+	for range 2 {
+		w = execute(quote.ID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("execute status=%d, want %d, body: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var executed types.Quote
+		if err := json.Unmarshal(w.Body.Bytes(), &executed); err != nil {
+			t.Fatalf("cannot unmarshal: %v", err)
+		}
+		if executed.ExecutedAt == nil {
+			t.Fatal("expected executed_at to be set")
+		}
+		executions = append(executions, executed)
+	}
+	if !executions[0].ExecutedAt.Equal(*executions[1].ExecutedAt) {
+		t.Fatalf("repeated execution changed executed_at: %s, %s", executions[0].ExecutedAt, executions[1].ExecutedAt)
+	}
+
+	cases := []struct {
+		name          string
+		response      *httptest.ResponseRecorder
+		wantStatus    int
+		wantErrorCode string
+	}{
+		{"malformed body", create(`{"from":"EUR"`), 400, CodeInvalidRequest},
+		{"missing currency", create(`{"from":"EUR","amount":"1"}`), 400, CodeInvalidRequest},
+		{"non positive amount", create(`{"from":"EUR","to":"PLN","amount":"0"}`), 400, CodeInvalidRequest},
+		{"unknown currency", create(`{"from":"EUR","to":"XYZ","amount":"1"}`), 404, CodeUnknownCurrency},
+		{"unknown quote", execute("missing"), 404, CodeQuoteNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.response.Code != tc.wantStatus {
+				t.Fatalf("response status=%d, want %d", tc.response.Code, tc.wantStatus)
+			}
+			assertErrorCode(t, tc.response, tc.wantErrorCode)
+		})
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	cases := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"invalid input", &types.InvalidInputError{Field: "amount"}, 400, CodeInvalidRequest},
+		{"wrapped unknown currency", fmt.Errorf("wrapped: %w", &types.UnknownCurrencyError{Currency: "XYZ"}), 404, CodeUnknownCurrency},
+		{"provider error", &types.ProviderError{Provider: "test", Err: errors.New("bad json")}, 502, CodeProviderError},
+		{"provider unavailable", &types.ProviderError{Provider: "test", Unavailable: true, Err: errors.New("timeout")}, 503, CodeProviderUnavailable},
+		{"quote not found", &types.QuoteNotFoundError{ID: "abc"}, 404, CodeQuoteNotFound},
+		{"quote expired", fmt.Errorf("wrapped: %w", &types.QuoteExpiredError{ID: "abc"}), 410, CodeQuoteExpired},
+		{"cancelled request", fmt.Errorf("wrapped: %w", context.Canceled), 504, CodeRequestTimeout},
+		{"upstream timeout", fmt.Errorf("wrapped: %w", &types.ProviderError{Provider: "test", Unavailable: true, Err: fmt.Errorf("GET: %w", context.DeadlineExceeded)}), 504, CodeRequestTimeout},
+		{"body too large", fmt.Errorf("wrapped: %w", &http.MaxBytesError{Limit: 10}), 413, CodeRequestTooLarge},
+		{"unexpected error", errors.New("boom"), 500, CodeInternalError},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, response := errorResponse(tc.err)
+			if status != tc.wantStatus {
+				t.Fatalf("status=%d, want %d", status, tc.wantStatus)
+			}
+			if response.Code != tc.wantCode {
+				t.Fatalf("code=%q, want %q", response.Code, tc.wantCode)
+			}
+		})
+	}
+}
+
+func assertErrorCode(t *testing.T, w *httptest.ResponseRecorder, wantCode string) {
+	t.Helper()
+	var got ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("expected valid JSON error: %v", err)
+	}
+	if got.Code != wantCode {
+		t.Fatalf("error code=%q, want %q", got.Code, wantCode)
+	}
+	if got.Message == "" {
+		t.Fatal("expected non-empty error message")
+	}
 }
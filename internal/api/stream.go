@@ -0,0 +1,120 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+// maxStreamCurrencies bounds the pairs computed for every stream on each refresh.
+const maxStreamCurrencies = 50
+
+// streamHeartbeatInterval keeps idle streams from being closed by proxies.
+var streamHeartbeatInterval = 15 * time.Second
+
+type ratesEvent struct {
+	Source    string                `json:"source"`
+	Timestamp time.Time             `json:"timestamp"`
+	Rates     []types.ConvertedRate `json:"rates"`
+}
+
+// StreamRates sends a rates event with the current rates and a new one every
+// time a refresh changes them. Event ids are derived from the rates, so a
+// client resuming with Last-Event-ID is not sent rates it already has.
+func (s *GinServer) StreamRates(c *gin.Context) {
+	if err := requireQueryParams(c, "currencies"); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	validatedCurrencies, err := validateCurrencies(strings.Split(c.Query("currencies"), ","))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	if len(validatedCurrencies) > maxStreamCurrencies {
+		respondWithError(c, &types.InvalidInputError{
+			Field:  "currencies",
+			Value:  c.Query("currencies"),
+			Reason: fmt.Sprintf("at most %d currencies are allowed", maxStreamCurrencies),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	sub := s.streams.Subscribe()
+	defer sub.Close()
+
+	rates, err := s.converter.GetCurrenciesRates(ctx, validatedCurrencies)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	setRatesSourceHeader(c, rates.Source)
+	c.Header("Content-Type", sse.ContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	lastID := c.GetHeader("Last-Event-ID")
+	send := func(rates types.CurrenciesRates) error {
+		event, err := newRatesEvent(rates)
+		if err != nil {
+			return err
+		}
+		if event.Id != lastID {
+			lastID = event.Id
+			c.Render(-1, event)
+			c.Writer.Flush()
+		}
+		return nil
+	}
+	if err := send(rates); err != nil {
+		respondWithError(c, err)
+		return
+	}
+	// a resumed stream may have nothing to send yet, flush the headers anyway
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.Done:
+			return
+		case <-heartbeat.C:
+			c.Render(-1, sse.Event{Event: "heartbeat", Data: time.Now().UTC().Format(time.RFC3339)})
+			c.Writer.Flush()
+		case <-sub.Updates:
+			rates, err := s.converter.GetCurrenciesRates(ctx, validatedCurrencies)
+			if err == nil {
+				err = send(rates)
+			}
+			if err != nil {
+				logrus.Warn("could not send rates stream update. err: ", err)
+			}
+		}
+	}
+}
+
+func newRatesEvent(rates types.CurrenciesRates) (sse.Event, error) {
+	data, err := json.Marshal(ratesEvent{Source: rates.Source, Timestamp: rates.Timestamp, Rates: rates.Rates})
+	if err != nil {
+		return sse.Event{}, fmt.Errorf("could not encode rates event, err: %w", err)
+	}
+	ratesJSON, err := json.Marshal(rates.Rates)
+	if err != nil {
+		return sse.Event{}, fmt.Errorf("could not encode rates event, err: %w", err)
+	}
+	hash := sha256.Sum256(ratesJSON)
+	return sse.Event{Id: hex.EncodeToString(hash[:8]), Event: "rates", Data: string(data)}, nil
+}
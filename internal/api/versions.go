@@ -0,0 +1,57 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Root routes were deprecated when /v1 was introduced and are removed at
+// legacyRoutesSunset.
+var (
+	legacyRoutesDeprecatedAt = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	legacyRoutesSunset       = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// versionGroup mounts an API version under prefix, behind API key auth when it
+// is enabled. Every version registers its own handlers, so that a new version
+// can change response types without affecting clients of the older ones.
+func (s *GinServer) versionGroup(prefix string, handlers ...gin.HandlerFunc) *gin.RouterGroup {
+	group := s.router.Group(prefix, handlers...)
+	if s.auth != nil {
+		group.Use(s.authenticate)
+	}
+	return group
+}
+
+func (s *GinServer) registerV1(routes gin.IRoutes) {
+	routes.GET("/rates", s.GetRates)
+	routes.GET("/rates/historical", s.GetHistoricalRates)
+	routes.GET("/rates/matrix", s.GetRatesMatrix)
+	routes.GET("/latest", s.GetLatestRates)
+	routes.GET("/currencies", s.GetCurrencies)
+	routes.GET("/exchange", s.ExchangeCurrencies)
+	routes.GET("/convert", s.ConvertCurrencies)
+	routes.POST("/convert/batch", s.ConvertBatch)
+	routes.GET("/timeseries", s.GetTimeSeries)
+	routes.POST("/quotes", s.CreateQuote)
+	routes.POST("/quotes/:id/execute", s.ExecuteQuote)
+	routes.GET("/stream/rates", s.StreamRates)
+	routes.GET("/nbp/bid-ask", s.GetNBPBidAskRates)
+	routes.GET("/nbp/tax-rate", s.GetNBPTaxRate)
+}
+
+// deprecatedAliasOf marks responses of alias routes as deprecated (RFC 9745)
+// with a sunset date (RFC 8594) and links the same route under successorPrefix.
+func deprecatedAliasOf(successorPrefix string) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", legacyRoutesDeprecatedAt.Unix())
+	sunset := legacyRoutesSunset.Format(http.TimeFormat)
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		c.Header("Sunset", sunset)
+		c.Header("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, successorPrefix, c.Request.URL.Path))
+		c.Next()
+	}
+}
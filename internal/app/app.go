@@ -3,17 +3,30 @@ package app
 import (
 	"context"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/wojcikp/currency-converter/internal/api"
+	"github.com/wojcikp/currency-converter/internal/auth"
 	"github.com/wojcikp/currency-converter/internal/config"
+	cryptoratesprovider "github.com/wojcikp/currency-converter/internal/crypto_rates_provider"
 	currencyconverter "github.com/wojcikp/currency-converter/internal/currency_converter"
-	exchangeratesprovider "github.com/wojcikp/currency-converter/internal/exchange_rates_provider"
+	"github.com/wojcikp/currency-converter/internal/fees"
+	"github.com/wojcikp/currency-converter/internal/health"
+	"github.com/wojcikp/currency-converter/internal/metrics"
+	"github.com/wojcikp/currency-converter/internal/quotes"
+	ratescache "github.com/wojcikp/currency-converter/internal/rates_cache"
+	ratesstream "github.com/wojcikp/currency-converter/internal/rates_stream"
+	"github.com/wojcikp/currency-converter/internal/storage"
+	"github.com/wojcikp/currency-converter/internal/types"
 )
 
 type App struct {
-	server *api.GinServer
+	server     *api.GinServer
+	ratesCache *ratescache.CachedRatesProvider
+	nbpCache   *ratescache.CachedNBPRatesProvider
+	store      *storage.SQLiteStore
 }
 
 func BuildApp() (*App, error) {
@@ -22,20 +35,113 @@ func BuildApp() (*App, error) {
 		return nil, err
 	}
 	logrus.Info("Application config loaded successfully")
+	return NewApp(config)
+}
+
+func NewApp(config *config.Config) (*App, error) {
+	appMetrics := metrics.New()
+
+	ratesProvider, err := newRatesProvider(config, appMetrics)
+	if err != nil {
+		return nil, err
+	}
+	logrus.Infof("Rates provider initialized, providers: %s", strings.Join(config.RatesProviders, ", "))
 
-	ratesProvider, err := exchangeratesprovider.NewExchangeRatesProvider(config.OpenExchangeAppID)
+	cryptoRatesProvider, err := cryptoratesprovider.NewCryptoRatesProvider(config.CryptoRatesBaseURL, config.CryptoTokens)
 	if err != nil {
 		return nil, err
 	}
-	logrus.Info("Rates provider initialized")
+	logrus.Infof("Crypto rates provider initialized, tokens: %d", len(config.CryptoTokens))
+
+	var fiatRates types.RatesProvider = ratesProvider
+	var cryptoRates types.CryptoRatesProvider = appMetrics.InstrumentCryptoRatesProvider("coingecko", cryptoRatesProvider)
+	var store *storage.SQLiteStore
+	if config.StoragePath != "" {
+		store, err = storage.NewSQLiteStore(config.StoragePath)
+		if err != nil {
+			return nil, err
+		}
+		fiatRates = storage.NewRecordingRatesProvider(fiatRates, store)
+		cryptoRates = storage.NewRecordingCryptoRatesProvider(cryptoRates, store)
+		logrus.Infof("Rates snapshot store opened: %s", config.StoragePath)
+	} else {
+		logrus.Warn("STORAGE_PATH env variable not set, rates snapshots will not be persisted")
+	}
 
-	converter := currencyconverter.NewConverter(ratesProvider)
+	ratesCache := ratescache.NewCachedRatesProvider(fiatRates, cryptoRates, config.RatesRefreshInterval)
+	ratesCache.Start(context.Background())
+	appMetrics.RegisterRatesCache(ratesCache)
+	logrus.Infof("Rates cache started, refresh interval: %s", config.RatesRefreshInterval)
+
+	var feeSchedule fees.Schedule
+	if config.FeesPath != "" {
+		feeSchedule, err = fees.LoadSchedule(config.FeesPath)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("Fee schedule loaded: %s", config.FeesPath)
+	}
+
+	converter := currencyconverter.NewConverter(ratesCache, ratesCache, feeSchedule, currencyconverter.WithSnapshotProvider(ratesCache))
 	logrus.Info("Currency converter initialized")
 
-	server := api.NewGinServer(config.ServerPort, converter)
+	// quotes share the instrumented converter, so conversions priced for a
+	// quote are counted in the conversion metrics like any other
+	instrumentedConverter := appMetrics.InstrumentConverter(converter)
+	quoteService := quotes.NewService(instrumentedConverter, newQuotesStore(config, store), config.QuoteTTL)
+	logrus.Infof("Quote service initialized, store: %s, ttl: %s", config.QuotesStore, config.QuoteTTL)
+
+	var storeCheck health.Store
+	if store != nil {
+		storeCheck = store
+	}
+	checker := health.NewChecker(ratesCache, storeCheck, config.MaxSnapshotAge, config.ReadinessRequireCrypto)
+
+	authenticator, err := newAuthenticator(config, store)
+	if err != nil {
+		return nil, err
+	}
+
+	// NBP bid/ask and tax rates are quoted in PLN, they are served from NBP
+	// whichever providers back the USD snapshot
+	nbpRates, err := newNBPRatesProvider(config)
+	if err != nil {
+		return nil, err
+	}
+	nbpCache := ratescache.NewCachedNBPRatesProvider(appMetrics.InstrumentNBPRatesProvider("nbp", nbpRates), config.RatesRefreshInterval)
+	nbpCache.Start(context.Background())
+	logrus.Infof("NBP rates cache started, refresh interval: %s", config.RatesRefreshInterval)
+
+	server := api.NewGinServer(config.ServerPort, instrumentedConverter, quoteService, appMetrics, checker, authenticator, ratesstream.NewHub(ratesCache), nbpCache)
 	logrus.Info("Gin server initialized")
 
-	return &App{server}, nil
+	return &App{server, ratesCache, nbpCache, store}, nil
+}
+
+func newQuotesStore(cfg *config.Config, store *storage.SQLiteStore) quotes.Store {
+	if cfg.QuotesStore == config.QuotesStoreSQLite {
+		return store
+	}
+	return quotes.NewMemoryStore()
+}
+
+// newAuthenticator returns nil when API keys are disabled.
+func newAuthenticator(cfg *config.Config, store *storage.SQLiteStore) (*auth.Authenticator, error) {
+	switch cfg.APIKeysStore {
+	case config.APIKeysStoreFile:
+		keys, err := auth.LoadKeyFile(cfg.APIKeysPath)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("API keys loaded: %s", cfg.APIKeysPath)
+		return auth.NewAuthenticator(keys), nil
+	case config.APIKeysStoreSQLite:
+		logrus.Info("API keys are looked up in the rates snapshot store")
+		return auth.NewAuthenticator(store), nil
+	default:
+		logrus.Warn("API_KEYS_STORE env variable not set, the API is open to everyone")
+		return nil, nil
+	}
 }
 
 func (a *App) Run() {
@@ -52,5 +158,14 @@ func (a *App) Shutdown() error {
 		return err
 	}
 	logrus.Info("Server is off")
+	a.ratesCache.Stop()
+	a.nbpCache.Stop()
+	logrus.Info("Rates caches stopped")
+	if a.store != nil {
+		if err := a.store.Close(); err != nil {
+			return err
+		}
+		logrus.Info("Rates snapshot store closed")
+	}
 	return nil
 }
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/wojcikp/currency-converter/internal/config"
+	ecbprovider "github.com/wojcikp/currency-converter/internal/ecb_provider"
+	exchangeratesprovider "github.com/wojcikp/currency-converter/internal/exchange_rates_provider"
+	fallbackprovider "github.com/wojcikp/currency-converter/internal/fallback_provider"
+	fileratesprovider "github.com/wojcikp/currency-converter/internal/file_rates_provider"
+	"github.com/wojcikp/currency-converter/internal/metrics"
+	nbpprovider "github.com/wojcikp/currency-converter/internal/nbp_provider"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+// newRatesProvider builds the configured fiat providers, chained in order of
+// preference when more than one is configured. Each provider is instrumented
+// on its own, so upstream metrics are reported per provider.
+func newRatesProvider(cfg *config.Config, m *metrics.Metrics) (types.RatesProvider, error) {
+	var providers []fallbackprovider.NamedProvider
+	for _, name := range cfg.RatesProviders {
+		provider, err := newNamedRatesProvider(name, cfg)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, fallbackprovider.NamedProvider{
+			Name:     name,
+			Provider: m.InstrumentRatesProvider(name, provider),
+			Timeout:  cfg.RatesProviderTimeout,
+		})
+	}
+	if len(providers) == 1 {
+		return providers[0].Provider, nil
+	}
+	return fallbackprovider.NewFallbackRatesProvider(providers...)
+}
+
+func newNamedRatesProvider(name string, cfg *config.Config) (types.RatesProvider, error) {
+	switch name {
+	case config.ProviderOpenExchange:
+		return exchangeratesprovider.NewExchangeRatesProvider(cfg.OpenExchangeAppID, exchangeRatesProviderOptions(cfg)...)
+	case config.ProviderECB:
+		var opts []ecbprovider.Option
+		if cfg.ECBBaseURL != "" {
+			opts = append(opts, ecbprovider.WithBaseURL(cfg.ECBBaseURL))
+		}
+		return ecbprovider.NewECBRatesProvider(opts...)
+	case config.ProviderNBP:
+		return newNBPRatesProvider(cfg)
+	case config.ProviderFile:
+		return fileratesprovider.NewFileRatesProvider(cfg.RatesFilePath)
+	default:
+		return nil, fmt.Errorf("unknown rates provider: %s", name)
+	}
+}
+
+func newNBPRatesProvider(cfg *config.Config) (*nbpprovider.NBPRatesProvider, error) {
+	opts := []nbpprovider.Option{nbpprovider.WithTables(cfg.NBPTables...)}
+	if cfg.NBPBaseURL != "" {
+		opts = append(opts, nbpprovider.WithBaseURL(cfg.NBPBaseURL))
+	}
+	return nbpprovider.NewNBPRatesProvider(opts...)
+}
+
+func exchangeRatesProviderOptions(cfg *config.Config) []exchangeratesprovider.Option {
+	opts := []exchangeratesprovider.Option{exchangeratesprovider.WithTimeout(cfg.OpenExchangeTimeout)}
+	if cfg.OpenExchangeBaseURL != "" {
+		opts = append(opts, exchangeratesprovider.WithBaseURL(cfg.OpenExchangeBaseURL))
+	}
+	if cfg.OpenExchangeUserAgent != "" {
+		opts = append(opts, exchangeratesprovider.WithUserAgent(cfg.OpenExchangeUserAgent))
+	}
+	if cfg.OpenExchangeTransport != nil {
+		opts = append(opts, exchangeratesprovider.WithTransport(cfg.OpenExchangeTransport))
+	}
+	return opts
+}
@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+func newTestAuthenticator(t *testing.T, key types.APIKey, now *time.Time) *Authenticator {
+	t.Helper()
+	keys, err := NewFileKeyStore(key)
+	if err != nil {
+		t.Fatalf("NewFileKeyStore error: %v", err)
+	}
+	authenticator := NewAuthenticator(keys)
+	authenticator.now = func() time.Time { return *now }
+	return authenticator
+}
+
+func TestAuthenticateTokenBucket(t *testing.T) {
+	now := time.Date(2025, time.June, 30, 12, 0, 0, 0, time.UTC)
+	authenticator := newTestAuthenticator(t, types.APIKey{
+		ID: "partner", Hash: HashKey("secret"), RatePerSecond: 2, Burst: 2, DailyQuota: 100,
+	}, &now)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := authenticator.Authenticate(ctx, "secret"); err != nil {
+			t.Fatalf("request %d error: %v", i, err)
+		}
+	}
+	_, limits, err := authenticator.Authenticate(ctx, "secret")
+	var rateLimitErr *types.RateLimitError
+	if !errors.As(err, &rateLimitErr) || rateLimitErr.Quota {
+		t.Fatalf("err=%v, want rate limit error", err)
+	}
+	if rateLimitErr.RetryAfter != 500*time.Millisecond {
+		t.Errorf("RetryAfter=%s, want 500ms", rateLimitErr.RetryAfter)
+	}
+	if limits.Remaining != 98 {
+		t.Errorf("Remaining=%d, want 98 since rejected requests are not counted", limits.Remaining)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	key, limits, err := authenticator.Authenticate(ctx, "secret")
+	if err != nil {
+		t.Fatalf("request after refill error: %v", err)
+	}
+	if key.ID != "partner" || limits.Remaining != 97 || limits.Limit != 100 {
+		t.Errorf("key=%s, limits=%+v, want partner with 97 of 100 remaining", key.ID, limits)
+	}
+}
+
+func TestAuthenticateDailyQuota(t *testing.T) {
+	now := time.Date(2025, time.June, 30, 23, 59, 0, 0, time.UTC)
+	authenticator := newTestAuthenticator(t, types.APIKey{
+		ID: "partner", Hash: HashKey("secret"), RatePerSecond: 100, Burst: 100, DailyQuota: 2,
+	}, &now)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := authenticator.Authenticate(ctx, "secret"); err != nil {
+			t.Fatalf("request %d error: %v", i, err)
+		}
+	}
+	_, limits, err := authenticator.Authenticate(ctx, "secret")
+	var rateLimitErr *types.RateLimitError
+	if !errors.As(err, &rateLimitErr) || !rateLimitErr.Quota {
+		t.Fatalf("err=%v, want quota error", err)
+	}
+	if rateLimitErr.RetryAfter != time.Minute {
+		t.Errorf("RetryAfter=%s, want 1m until midnight", rateLimitErr.RetryAfter)
+	}
+	wantReset := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	if limits.Remaining != 0 || !limits.Reset.Equal(wantReset) {
+		t.Errorf("limits=%+v, want 0 remaining until %s", limits, wantReset)
+	}
+
+	now = now.Add(time.Minute)
+	if _, limits, err := authenticator.Authenticate(ctx, "secret"); err != nil || limits.Remaining != 1 {
+		t.Fatalf("request on the next day remaining=%d, err=%v, want 1 remaining", limits.Remaining, err)
+	}
+}
+
+func TestAuthenticateUnauthorized(t *testing.T) {
+	now := time.Date(2025, time.June, 30, 12, 0, 0, 0, time.UTC)
+	authenticator := newTestAuthenticator(t, types.APIKey{
+		ID: "partner", Hash: HashKey("secret"), RatePerSecond: 1, Burst: 1, DailyQuota: 1,
+	}, &now)
+
+	for _, key := range []string{"", "Secret", HashKey("secret")} {
+		var unauthorizedErr *types.UnauthorizedError
+		if _, _, err := authenticator.Authenticate(context.Background(), key); !errors.As(err, &unauthorizedErr) {
+			t.Errorf("Authenticate(%q) err=%v, want unauthorized", key, err)
+		}
+	}
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	hash := HashKey("secret")
+	cases := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "valid",
+			content: `{"keys": [{"id": "partner", "hash": "` + hash + `", "rate_per_second": 5, "burst": 10, "daily_quota": 1000}]}`,
+		},
+		{
+			name:    "raw key instead of hash",
+			content: `{"keys": [{"id": "partner", "hash": "secret", "rate_per_second": 5, "burst": 10, "daily_quota": 1000}]}`,
+			wantErr: "hash must be a lower case hex encoded sha256",
+		},
+		{
+			name:    "missing quota",
+			content: `{"keys": [{"id": "partner", "hash": "` + hash + `", "rate_per_second": 5, "burst": 10}]}`,
+			wantErr: "daily_quota must be at least 1",
+		},
+		{
+			name: "duplicate id",
+			content: `{"keys": [{"id": "partner", "hash": "` + hash + `", "rate_per_second": 5, "burst": 10, "daily_quota": 1000},
+				{"id": "partner", "hash": "` + HashKey("other") + `", "rate_per_second": 5, "burst": 10, "daily_quota": 1000}]}`,
+			wantErr: "duplicate api key id: partner",
+		},
+		{
+			name:    "malformed json",
+			content: `{"keys": [`,
+			wantErr: "could not parse api keys file",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keys.json")
+			if err := os.WriteFile(path, []byte(tc.content), 0o600); err != nil {
+				t.Fatalf("cannot write keys file: %v", err)
+			}
+
+			store, err := LoadKeyFile(path)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err=%v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadKeyFile error: %v", err)
+			}
+			if _, ok, _ := store.LookupAPIKey(context.Background(), hash); !ok {
+				t.Fatal("expected key to be found by its hash")
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+// Limits describe the daily quota of the key after the request was counted.
+type Limits struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// Authenticator validates API keys and enforces their limits. Usage is kept
+// in memory, so quotas start over when the process restarts.
+type Authenticator struct {
+	keys KeyStore
+	now  func() time.Time
+
+	mu    sync.Mutex
+	usage map[string]*usage
+}
+
+type usage struct {
+	tokens  float64
+	updated time.Time
+	day     time.Time
+	used    int
+}
+
+func NewAuthenticator(keys KeyStore) *Authenticator {
+	return &Authenticator{keys: keys, now: time.Now, usage: map[string]*usage{}}
+}
+
+// Authenticate counts one request against the key. Limits are returned also
+// when the request is rejected for exceeding them.
+func (a *Authenticator) Authenticate(ctx context.Context, rawKey string) (types.APIKey, Limits, error) {
+	if rawKey == "" {
+		return types.APIKey{}, Limits{}, &types.UnauthorizedError{Reason: "missing api key"}
+	}
+	key, ok, err := a.keys.LookupAPIKey(ctx, HashKey(rawKey))
+	if err != nil {
+		return types.APIKey{}, Limits{}, fmt.Errorf("error during api key lookup, err: %w", err)
+	}
+	if !ok {
+		return types.APIKey{}, Limits{}, &types.UnauthorizedError{Reason: "invalid api key"}
+	}
+	limits, err := a.take(key)
+	return key, limits, err
+}
+
+func (a *Authenticator) take(key types.APIKey) (Limits, error) {
+	now := a.now().UTC()
+	day := now.Truncate(24 * time.Hour)
+	reset := day.Add(24 * time.Hour)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	u, ok := a.usage[key.ID]
+	if !ok {
+		u = &usage{tokens: float64(key.Burst), updated: now, day: day}
+		a.usage[key.ID] = u
+	}
+	if !u.day.Equal(day) {
+		u.day, u.used = day, 0
+	}
+	u.tokens = math.Min(float64(key.Burst), u.tokens+now.Sub(u.updated).Seconds()*key.RatePerSecond)
+	u.updated = now
+
+	limits := Limits{Limit: key.DailyQuota, Remaining: max(key.DailyQuota-u.used, 0), Reset: reset}
+	if u.used >= key.DailyQuota {
+		return limits, &types.RateLimitError{KeyID: key.ID, Quota: true, RetryAfter: reset.Sub(now)}
+	}
+	if u.tokens < 1 {
+		wait := time.Duration((1 - u.tokens) / key.RatePerSecond * float64(time.Second))
+		return limits, &types.RateLimitError{KeyID: key.ID, RetryAfter: wait}
+	}
+	u.tokens--
+	u.used++
+	limits.Remaining--
+	return limits, nil
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+var keyHashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// KeyStore looks API keys up by the SHA-256 hash of the raw key.
+type KeyStore interface {
+	LookupAPIKey(ctx context.Context, hash string) (types.APIKey, bool, error)
+}
+
+// HashKey returns the hex encoded SHA-256 hash under which a key is stored.
+// Keys are random tokens, so a fast hash is enough to keep them secret at rest.
+func HashKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// FileKeyStore serves keys read once from a JSON file.
+type FileKeyStore struct {
+	keys map[string]types.APIKey
+}
+
+type keyFile struct {
+	Keys []types.APIKey `json:"keys"`
+}
+
+func LoadKeyFile(path string) (*FileKeyStore, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read api keys file: %s, err: %w", path, err)
+	}
+	var file keyFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("could not parse api keys file: %s, err: %w", path, err)
+	}
+	store, err := NewFileKeyStore(file.Keys...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid api keys file: %s, err: %w", path, err)
+	}
+	return store, nil
+}
+
+func NewFileKeyStore(keys ...types.APIKey) (*FileKeyStore, error) {
+	store := &FileKeyStore{keys: make(map[string]types.APIKey, len(keys))}
+	ids := map[string]bool{}
+	for _, key := range keys {
+		if err := ValidateKey(key); err != nil {
+			return nil, err
+		}
+		if ids[key.ID] {
+			return nil, fmt.Errorf("duplicate api key id: %s", key.ID)
+		}
+		if _, ok := store.keys[key.Hash]; ok {
+			return nil, fmt.Errorf("api key: %s has the same hash as another key", key.ID)
+		}
+		ids[key.ID] = true
+		store.keys[key.Hash] = key
+	}
+	return store, nil
+}
+
+func (s *FileKeyStore) LookupAPIKey(ctx context.Context, hash string) (types.APIKey, bool, error) {
+	key, ok := s.keys[hash]
+	return key, ok, nil
+}
+
+func ValidateKey(key types.APIKey) error {
+	switch {
+	case key.ID == "":
+		return errors.New("api key id must not be empty")
+	case !keyHashPattern.MatchString(key.Hash):
+		return fmt.Errorf("api key: %s hash must be a lower case hex encoded sha256", key.ID)
+	case key.RatePerSecond <= 0:
+		return fmt.Errorf("api key: %s rate_per_second must be positive", key.ID)
+	case key.Burst < 1:
+		return fmt.Errorf("api key: %s burst must be at least 1", key.ID)
+	case key.DailyQuota < 1:
+		return fmt.Errorf("api key: %s daily_quota must be at least 1", key.ID)
+	}
+	return nil
+}
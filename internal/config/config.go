@@ -2,25 +2,250 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/wojcikp/currency-converter/internal/types"
 )
 
 type Config struct {
-	ServerPort        string
-	OpenExchangeAppID string
+	ServerPort            string
+	OpenExchangeAppID     string
+	OpenExchangeBaseURL   string
+	OpenExchangeTimeout   time.Duration
+	OpenExchangeUserAgent string
+	// OpenExchangeTransport is not read from env, it is meant to be set by
+	// callers that need to route upstream calls through a custom transport.
+	OpenExchangeTransport  http.RoundTripper
+	RatesRefreshInterval   time.Duration
+	CryptoRatesBaseURL     string
+	CryptoTokens           []types.CryptoToken
+	StoragePath            string
+	RatesProviders         []string
+	RatesProviderTimeout   time.Duration
+	RatesFilePath          string
+	ECBBaseURL             string
+	NBPBaseURL             string
+	NBPTables              []string
+	FeesPath               string
+	QuoteTTL               time.Duration
+	QuotesStore            string
+	MaxSnapshotAge         time.Duration
+	ReadinessRequireCrypto bool
+	// APIKeysStore is empty when API keys are disabled.
+	APIKeysStore string
+	APIKeysPath  string
 }
 
+const (
+	defaultCryptoRatesBaseURL = "https://api.coingecko.com/api/v3"
+	defaultCryptoTokens       = "BEER:beercoin-2:18:Beercoin,FLOKI:floki:18:FLOKI,GATE:gatechain-token:18:GateToken,USDT:tether:6:Tether,WBTC:wrapped-bitcoin:8:Wrapped Bitcoin"
+)
+
+const (
+	ProviderOpenExchange = "oxr"
+	ProviderFile         = "file"
+	ProviderECB          = "ecb"
+	ProviderNBP          = "nbp"
+)
+
+const (
+	QuotesStoreMemory = "memory"
+	QuotesStoreSQLite = "sqlite"
+)
+
+const (
+	APIKeysStoreFile   = "file"
+	APIKeysStoreSQLite = "sqlite"
+)
+
+var knownRatesProviders = []string{ProviderOpenExchange, ProviderECB, ProviderNBP, ProviderFile}
+
 func Load() (*Config, error) {
 	serverPort := os.Getenv("SERVER_PORT")
 	if serverPort == "" {
 		logrus.Warn("could not read SERVER_PORT env variable. Setting server port to default :8080")
 		serverPort = "8080"
 	}
+	ratesProviders := listEnv("RATES_PROVIDERS", ProviderOpenExchange)
+	for _, provider := range ratesProviders {
+		if !slices.Contains(knownRatesProviders, provider) {
+			return nil, fmt.Errorf("unknown rates provider in RATES_PROVIDERS env variable: %s, expected one of: %s", provider, strings.Join(knownRatesProviders, ", "))
+		}
+	}
+	ratesProviderTimeout, err := durationEnv("RATES_PROVIDER_TIMEOUT", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
 	openexchangeAppId := os.Getenv("OPENEXCHANGE_APP_ID")
-	if openexchangeAppId == "" {
+	if openexchangeAppId == "" && slices.Contains(ratesProviders, ProviderOpenExchange) {
 		return nil, errors.New("could not read OPENEXCHANGE_APP_ID env variable. provide OPENEXCHANGE_APP_ID env variable to run application")
 	}
-	return &Config{serverPort, openexchangeAppId}, nil
+	ratesFilePath := os.Getenv("RATES_FILE_PATH")
+	if ratesFilePath == "" && slices.Contains(ratesProviders, ProviderFile) {
+		return nil, errors.New("could not read RATES_FILE_PATH env variable. provide RATES_FILE_PATH env variable to use the file rates provider")
+	}
+	nbpTables := listEnv("NBP_TABLES", "a")
+	if !slices.Contains(nbpTables, "a") {
+		return nil, fmt.Errorf("NBP_TABLES env variable must contain table a, table b has no USD mid rate to rebase the rates on, value: %s", strings.Join(nbpTables, ","))
+	}
+	openexchangeTimeout, err := durationEnv("OPENEXCHANGE_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	ratesRefreshInterval, err := durationEnv("RATES_REFRESH_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	cryptoRatesBaseURL := os.Getenv("CRYPTO_RATES_BASE_URL")
+	if cryptoRatesBaseURL == "" {
+		cryptoRatesBaseURL = defaultCryptoRatesBaseURL
+	}
+	cryptoTokensEnv := os.Getenv("CRYPTO_TOKENS")
+	if cryptoTokensEnv == "" {
+		cryptoTokensEnv = defaultCryptoTokens
+	}
+	cryptoTokens, err := parseCryptoTokens(cryptoTokensEnv)
+	if err != nil {
+		return nil, err
+	}
+	quoteTTL, err := durationEnv("QUOTE_TTL", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	maxSnapshotAge, err := durationEnv("READINESS_MAX_SNAPSHOT_AGE", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	readinessRequireCrypto, err := boolEnv("READINESS_REQUIRE_CRYPTO", false)
+	if err != nil {
+		return nil, err
+	}
+	quotesStore := strings.ToLower(os.Getenv("QUOTES_STORE"))
+	if quotesStore == "" {
+		quotesStore = QuotesStoreMemory
+	}
+	if quotesStore != QuotesStoreMemory && quotesStore != QuotesStoreSQLite {
+		return nil, fmt.Errorf("unknown quotes store in QUOTES_STORE env variable: %s, expected %s or %s", quotesStore, QuotesStoreMemory, QuotesStoreSQLite)
+	}
+	storagePath := os.Getenv("STORAGE_PATH")
+	if quotesStore == QuotesStoreSQLite && storagePath == "" {
+		return nil, errors.New("could not read STORAGE_PATH env variable. provide STORAGE_PATH env variable to store quotes in sqlite")
+	}
+	apiKeysStore := strings.ToLower(os.Getenv("API_KEYS_STORE"))
+	if apiKeysStore != "" && apiKeysStore != APIKeysStoreFile && apiKeysStore != APIKeysStoreSQLite {
+		return nil, fmt.Errorf("unknown api keys store in API_KEYS_STORE env variable: %s, expected %s or %s", apiKeysStore, APIKeysStoreFile, APIKeysStoreSQLite)
+	}
+	apiKeysPath := os.Getenv("API_KEYS_PATH")
+	if apiKeysStore == APIKeysStoreFile && apiKeysPath == "" {
+		return nil, errors.New("could not read API_KEYS_PATH env variable. provide API_KEYS_PATH env variable to load api keys from a file")
+	}
+	if apiKeysStore == APIKeysStoreSQLite && storagePath == "" {
+		return nil, errors.New("could not read STORAGE_PATH env variable. provide STORAGE_PATH env variable to load api keys from sqlite")
+	}
+	return &Config{
+		ServerPort:             serverPort,
+		OpenExchangeAppID:      openexchangeAppId,
+		OpenExchangeBaseURL:    os.Getenv("OPENEXCHANGE_BASE_URL"),
+		OpenExchangeTimeout:    openexchangeTimeout,
+		OpenExchangeUserAgent:  os.Getenv("OPENEXCHANGE_USER_AGENT"),
+		RatesRefreshInterval:   ratesRefreshInterval,
+		CryptoRatesBaseURL:     cryptoRatesBaseURL,
+		CryptoTokens:           cryptoTokens,
+		StoragePath:            storagePath,
+		RatesProviders:         ratesProviders,
+		RatesProviderTimeout:   ratesProviderTimeout,
+		RatesFilePath:          ratesFilePath,
+		ECBBaseURL:             os.Getenv("ECB_BASE_URL"),
+		NBPBaseURL:             os.Getenv("NBP_BASE_URL"),
+		NBPTables:              nbpTables,
+		FeesPath:               os.Getenv("FEES_PATH"),
+		QuoteTTL:               quoteTTL,
+		QuotesStore:            quotesStore,
+		MaxSnapshotAge:         maxSnapshotAge,
+		ReadinessRequireCrypto: readinessRequireCrypto,
+		APIKeysStore:           apiKeysStore,
+		APIKeysPath:            apiKeysPath,
+	}, nil
+}
+
+// parseCryptoTokens parses a comma separated list of SYMBOL:source_id:decimal_places[:name]
+// entries, the symbol doubles as the name when none is given.
+func parseCryptoTokens(value string) ([]types.CryptoToken, error) {
+	var tokens []types.CryptoToken
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) < 3 || len(parts) > 4 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("could not parse CRYPTO_TOKENS entry: %s, expected SYMBOL:source_id:decimal_places[:name]", entry)
+		}
+		decimalPlaces, err := strconv.Atoi(parts[2])
+		if err != nil || decimalPlaces < 0 {
+			return nil, fmt.Errorf("could not parse decimal places of CRYPTO_TOKENS entry: %s", entry)
+		}
+		token := types.CryptoToken{
+			Symbol:        strings.ToUpper(parts[0]),
+			SourceID:      parts[1],
+			Name:          strings.ToUpper(parts[0]),
+			DecimalPlaces: decimalPlaces,
+		}
+		if len(parts) == 4 && strings.TrimSpace(parts[3]) != "" {
+			token.Name = strings.TrimSpace(parts[3])
+		}
+		tokens = append(tokens, token)
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("CRYPTO_TOKENS env variable does not contain any token")
+	}
+	return tokens, nil
+}
+
+func listEnv(key, defaultValue string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		value = defaultValue
+	}
+	var values []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.ToLower(strings.TrimSpace(entry)); entry != "" {
+			values = append(values, entry)
+		}
+	}
+	return values
+}
+
+func durationEnv(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse %s env variable as duration, value: %s, err: %w", key, value, err)
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("%s env variable must be a positive duration, value: %s", key, value)
+	}
+	return duration, nil
+}
+
+func boolEnv(key string, defaultValue bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("could not parse %s env variable as boolean, value: %s, err: %w", key, value, err)
+	}
+	return parsed, nil
 }
@@ -0,0 +1,105 @@
+package cryptoratesprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+const providerName = "coingecko"
+
+// CryptoRatesProvider fetches USD prices for the configured tokens from a
+// CoinGecko compatible /simple/price API.
+type CryptoRatesProvider struct {
+	baseURL    string
+	tokens     []types.CryptoToken
+	httpClient *http.Client
+}
+
+type tokenPrice struct {
+	USD           decimal.Decimal `json:"usd"`
+	LastUpdatedAt int64           `json:"last_updated_at"`
+}
+
+func NewCryptoRatesProvider(baseURL string, tokens []types.CryptoToken) (*CryptoRatesProvider, error) {
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("no crypto tokens configured for %s provider", providerName)
+	}
+	return &CryptoRatesProvider{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		tokens:     tokens,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (p *CryptoRatesProvider) GetCryptoExchangeRates(ctx context.Context) (types.CryptoRates, error) {
+	ids := make([]string, 0, len(p.tokens))
+	for _, token := range p.tokens {
+		ids = append(ids, token.SourceID)
+	}
+	query := url.Values{}
+	query.Set("ids", strings.Join(ids, ","))
+	query.Set("vs_currencies", "usd")
+	query.Set("include_last_updated_at", "true")
+	priceURL := fmt.Sprintf("%s/simple/price?%s", p.baseURL, query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, priceURL, nil)
+	if err != nil {
+		return types.CryptoRates{}, fmt.Errorf("creating request err: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return types.CryptoRates{}, &types.ProviderError{
+			Provider:    providerName,
+			Unavailable: true,
+			Err:         fmt.Errorf("error during crypto prices api GET, err: %w", err),
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return types.CryptoRates{}, &types.ProviderError{
+			Provider:    providerName,
+			Unavailable: resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError,
+			Err:         fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body)),
+		}
+	}
+
+	var prices map[string]tokenPrice
+	if err := json.NewDecoder(resp.Body).Decode(&prices); err != nil {
+		return types.CryptoRates{}, &types.ProviderError{Provider: providerName, Err: fmt.Errorf("json decoding error: %w", err)}
+	}
+
+	rates := make(map[string]types.CryptoCurrencyInfo, len(p.tokens))
+	var timestamp time.Time
+	for _, token := range p.tokens {
+		price, ok := prices[token.SourceID]
+		if !ok || !price.USD.IsPositive() {
+			return types.CryptoRates{}, &types.ProviderError{
+				Provider: providerName,
+				Err:      fmt.Errorf("no usd price for token: %s (id: %s)", token.Symbol, token.SourceID),
+			}
+		}
+		rates[token.Symbol] = types.CryptoCurrencyInfo{Name: token.Name, DecimalPlaces: token.DecimalPlaces, RateToUSD: price.USD}
+		// the snapshot is only as fresh as its oldest price
+		updatedAt := time.Unix(price.LastUpdatedAt, 0).UTC()
+		if price.LastUpdatedAt > 0 && (timestamp.IsZero() || updatedAt.Before(timestamp)) {
+			timestamp = updatedAt
+		}
+	}
+	if timestamp.IsZero() {
+		timestamp = time.Now().UTC()
+	}
+	return types.CryptoRates{Source: providerName, Timestamp: timestamp, Rates: rates}, nil
+}
@@ -0,0 +1,97 @@
+package cryptoratesprovider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+var testTokens = []types.CryptoToken{
+	{Symbol: "WBTC", SourceID: "wrapped-bitcoin", DecimalPlaces: 8},
+	{Symbol: "USDT", SourceID: "tether", DecimalPlaces: 6},
+}
+
+func TestGetCryptoExchangeRates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/simple/price" {
+			t.Errorf("path=%s, want /simple/price", r.URL.Path)
+		}
+		if ids := r.URL.Query().Get("ids"); ids != "wrapped-bitcoin,tether" {
+			t.Errorf("ids=%s, want wrapped-bitcoin,tether", ids)
+		}
+		w.Write([]byte(`{"wrapped-bitcoin":{"usd":57037.22,"last_updated_at":1751284800},"tether":{"usd":0.999,"last_updated_at":1751284740}}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewCryptoRatesProvider(server.URL+"/", testTokens)
+	if err != nil {
+		t.Fatalf("NewCryptoRatesProvider error: %v", err)
+	}
+	rates, err := provider.GetCryptoExchangeRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetCryptoExchangeRates error: %v", err)
+	}
+
+	if rates.Source != providerName {
+		t.Errorf("source=%s, want %s", rates.Source, providerName)
+	}
+	if wantTimestamp := time.Unix(1751284740, 0).UTC(); !rates.Timestamp.Equal(wantTimestamp) {
+		t.Errorf("timestamp=%s, want oldest price update %s", rates.Timestamp, wantTimestamp)
+	}
+
+	want := map[string]types.CryptoCurrencyInfo{
+		"WBTC": {DecimalPlaces: 8, RateToUSD: decimal.RequireFromString("57037.22")},
+		"USDT": {DecimalPlaces: 6, RateToUSD: decimal.RequireFromString("0.999")},
+	}
+	for symbol, info := range want {
+		got, ok := rates.Rates[symbol]
+		if !ok {
+			t.Fatalf("missing %s in rates", symbol)
+		}
+		if got.DecimalPlaces != info.DecimalPlaces || !got.RateToUSD.Equal(info.RateToUSD) {
+			t.Errorf("%s=%+v, want %+v", symbol, got, info)
+		}
+	}
+}
+
+func TestGetCryptoExchangeRatesErrors(t *testing.T) {
+	cases := []struct {
+		name            string
+		status          int
+		body            string
+		wantUnavailable bool
+	}{
+		{"missing token", http.StatusOK, `{"wrapped-bitcoin":{"usd":57037.22}}`, false},
+		{"malformed body", http.StatusOK, `{"wrapped-bitcoin":`, false},
+		{"rate limited", http.StatusTooManyRequests, `{}`, true},
+		{"upstream down", http.StatusServiceUnavailable, `{}`, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			provider, err := NewCryptoRatesProvider(server.URL, testTokens)
+			if err != nil {
+				t.Fatalf("NewCryptoRatesProvider error: %v", err)
+			}
+			_, err = provider.GetCryptoExchangeRates(context.Background())
+			var providerErr *types.ProviderError
+			if !errors.As(err, &providerErr) {
+				t.Fatalf("err=%v, want *types.ProviderError", err)
+			}
+			if providerErr.Unavailable != tc.wantUnavailable {
+				t.Fatalf("unavailable=%t, want %t", providerErr.Unavailable, tc.wantUnavailable)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package currencyconverter
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/wojcikp/currency-converter/internal/currencies"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+// GetCurrencies lists the assets present in the current snapshots, fiat first,
+// each group sorted by code. An empty assetType lists both groups.
+func (c *Converter) GetCurrencies(ctx context.Context, assetType string) ([]types.CurrencyInfo, error) {
+	var catalogue []types.CurrencyInfo
+
+	if assetType == "" || assetType == types.AssetTypeFiat {
+		rates, err := c.exchangeRatesProvider.GetExchangeRates(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error during fetching exchange rates, err: %w", err)
+		}
+		var fiat []types.CurrencyInfo
+		for code := range rates.Rates {
+			info := types.CurrencyInfo{Code: code, Type: types.AssetTypeFiat}
+			if currency, ok := currencies.Lookup(code); ok {
+				info.Name, info.NumericCode, info.Symbol = currency.Name, currency.NumericCode, currency.Symbol
+				info.MinorUnits = &currency.MinorUnits
+			}
+			fiat = append(fiat, info)
+		}
+		catalogue = append(catalogue, sortedByCode(fiat)...)
+	}
+
+	if assetType == "" || assetType == types.AssetTypeCrypto {
+		cryptoRates, err := c.cryptoExchangeRatesProvider.GetCryptoExchangeRates(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error during fetching crypto exchange rates, err: %w", err)
+		}
+		var crypto []types.CurrencyInfo
+		for code, token := range cryptoRates.Rates {
+			crypto = append(crypto, types.CurrencyInfo{
+				Code:          code,
+				Type:          types.AssetTypeCrypto,
+				Name:          token.Name,
+				DecimalPlaces: &token.DecimalPlaces,
+			})
+		}
+		catalogue = append(catalogue, sortedByCode(crypto)...)
+	}
+
+	return catalogue, nil
+}
+
+func sortedByCode(infos []types.CurrencyInfo) []types.CurrencyInfo {
+	slices.SortFunc(infos, func(a, b types.CurrencyInfo) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+	return infos
+}
@@ -1,68 +1,295 @@
 package currencyconverter
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/shopspring/decimal"
+	"github.com/wojcikp/currency-converter/internal/currencies"
+	"github.com/wojcikp/currency-converter/internal/fees"
 	"github.com/wojcikp/currency-converter/internal/types"
 )
 
+// pivotCurrency is the currency every fiat and crypto rate is quoted against,
+// so conversions between any two assets are triangulated through it.
+const pivotCurrency = "USD"
+
 type Converter struct {
-	exchangeRatesProvider types.RatesProvider
+	exchangeRatesProvider       types.RatesProvider
+	cryptoExchangeRatesProvider types.CryptoRatesProvider
+	// snapshots is nil unless fiat and crypto rates can be read from the same
+	// refresh.
+	snapshots   types.SnapshotProvider
+	feeSchedule fees.Schedule
 }
 
-func NewConverter(ratesProvider types.RatesProvider) *Converter {
-	return &Converter{ratesProvider}
+type Option func(*Converter)
+
+// WithSnapshotProvider makes conversions that need fiat and crypto rates read
+// both from one refresh of snapshots instead of one read per provider.
+func WithSnapshotProvider(snapshots types.SnapshotProvider) Option {
+	return func(c *Converter) {
+		c.snapshots = snapshots
+	}
 }
 
-func (c *Converter) GetCurrenciesRates(ctx context.Context, currencies []string) ([]types.ConvertedRate, error) {
+func NewConverter(
+	ratesProvider types.RatesProvider,
+	cryptoRatesProvider types.CryptoRatesProvider,
+	feeSchedule fees.Schedule,
+	opts ...Option,
+) *Converter {
+	c := &Converter{
+		exchangeRatesProvider:       ratesProvider,
+		cryptoExchangeRatesProvider: cryptoRatesProvider,
+		feeSchedule:                 feeSchedule,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Converter) GetCurrenciesRates(ctx context.Context, currencies []string) (types.CurrenciesRates, error) {
 	rates, err := c.exchangeRatesProvider.GetExchangeRates(ctx)
 	if err != nil {
-		return []types.ConvertedRate{}, fmt.Errorf("error during fetching exchange rates, err: %w", err)
+		return types.CurrenciesRates{}, fmt.Errorf("error during fetching exchange rates, err: %w", err)
 	}
+	return convertRates(currencies, rates)
+}
 
-	if err = validateCurrencies(currencies, rates); err != nil {
-		return []types.ConvertedRate{}, err
+func (c *Converter) GetHistoricalCurrenciesRates(
+	ctx context.Context,
+	date time.Time,
+	currencies []string,
+) (types.CurrenciesRates, error) {
+	rates, err := c.exchangeRatesProvider.GetHistoricalExchangeRates(ctx, date)
+	if err != nil {
+		return types.CurrenciesRates{}, fmt.Errorf("error during fetching historical exchange rates for %s, err: %w", date.Format(time.DateOnly), err)
+	}
+	return convertRates(currencies, rates)
+}
+
+func convertRates(currencies []string, rates types.ExchangeRates) (types.CurrenciesRates, error) {
+	if err := validateCurrencies(currencies, rates); err != nil {
+		return types.CurrenciesRates{}, err
 	}
 
 	exchangePairs := getCurrencyPairsToExchange(currencies)
 
 	for i := range exchangePairs {
-		exchangePairs[i].Rate = rates[exchangePairs[i].To].Div(rates[exchangePairs[i].From])
+		exchangePairs[i].Rate = rates.Rates[exchangePairs[i].To].Div(rates.Rates[exchangePairs[i].From])
+	}
+	slices.SortFunc(exchangePairs, func(a, b types.ConvertedRate) int {
+		return cmp.Or(strings.Compare(a.From, b.From), strings.Compare(a.To, b.To))
+	})
+
+	return types.CurrenciesRates{Source: rates.Source, Timestamp: rates.Timestamp, Rates: exchangePairs}, nil
+}
+
+func (c *Converter) ConvertCurrencies(
+	ctx context.Context,
+	from, to string,
+	amount decimal.Decimal,
+) (types.ConvertedAmount, error) {
+	rates, err := c.exchangeRatesProvider.GetExchangeRates(ctx)
+	if err != nil {
+		return types.ConvertedAmount{}, fmt.Errorf("error during fetching exchange rates, err: %w", err)
 	}
 
-	return exchangePairs, nil
+	if err = validateCurrencies([]string{from, to}, rates); err != nil {
+		return types.ConvertedAmount{}, err
+	}
+
+	midRate := rates.Rates[to].Div(rates.Rates[from])
+	charge := c.feeSchedule.Apply(from, to, fees.Fiat, midRate, amount.Mul(midRate))
+	result, fee := charge.Gross, charge.Fee
+	if currency, ok := currencies.Lookup(to); ok {
+		result, fee = result.Round(int32(currency.MinorUnits)), fee.Round(int32(currency.MinorUnits))
+	}
+	netAmount, err := deductFee(to, amount, result, fee)
+	if err != nil {
+		return types.ConvertedAmount{}, err
+	}
+
+	return types.ConvertedAmount{
+		From:      from,
+		To:        to,
+		Amount:    amount,
+		Result:    result,
+		Rate:      charge.AppliedRate,
+		MidRate:   charge.MidRate,
+		Fee:       fee,
+		NetAmount: netAmount,
+		Source:    rates.Source,
+		Timestamp: rates.Timestamp,
+	}, nil
 }
 
-func (c *Converter) ConvertCryptoCurrencies(
+func (c *Converter) ExchangeCurrencies(
 	ctx context.Context,
 	from, to string,
 	amount decimal.Decimal,
-) (types.ExchangedCryptoCurrency, error) {
-	rates := c.exchangeRatesProvider.GetCryptoExchangeRates(ctx)
+) (types.ExchangedCurrency, error) {
+	rates, cryptoRates, err := c.fetchSnapshot(ctx)
+	if err != nil {
+		return types.ExchangedCurrency{}, err
+	}
+	return c.exchange(rates, cryptoRates, from, to, amount)
+}
+
+// ExchangeCurrenciesBatch converts every item against the same fiat and crypto
+// snapshot. Item failures are reported per item, only a failure to fetch the
+// snapshot fails the whole batch.
+func (c *Converter) ExchangeCurrenciesBatch(ctx context.Context, items []types.BatchConversionItem) (types.BatchConversions, error) {
+	rates, cryptoRates, err := c.fetchSnapshot(ctx)
+	if err != nil {
+		return types.BatchConversions{}, err
+	}
 
-	currencyFrom, ok := rates[from]
+	conversions := make([]types.BatchConversion, len(items))
+	for i, item := range items {
+		conversions[i].ID = item.ID
+		if item.From == "" || item.To == "" {
+			conversions[i].Err = &types.InvalidInputError{Field: "from/to", Value: item.From + "/" + item.To, Reason: "both currencies are required"}
+			continue
+		}
+		if !item.Amount.IsPositive() {
+			conversions[i].Err = &types.InvalidInputError{Field: "amount", Value: item.Amount.String(), Reason: "must be positive"}
+			continue
+		}
+		conversions[i].Result, conversions[i].Err = c.exchange(rates, cryptoRates, item.From, item.To, item.Amount)
+	}
+	return types.BatchConversions{
+		Source:          rates.Source,
+		Timestamp:       rates.Timestamp,
+		CryptoTimestamp: cryptoRates.Timestamp,
+		Conversions:     conversions,
+	}, nil
+}
+
+// fetchSnapshot reads fiat and crypto rates in one call when the provider
+// serves both, otherwise a refresh may land between the two reads.
+func (c *Converter) fetchSnapshot(ctx context.Context) (types.ExchangeRates, types.CryptoRates, error) {
+	if c.snapshots != nil {
+		rates, cryptoRates, err := c.snapshots.GetSnapshot(ctx)
+		if err != nil {
+			return types.ExchangeRates{}, types.CryptoRates{}, fmt.Errorf("error during fetching rates snapshot, err: %w", err)
+		}
+		return rates, cryptoRates, nil
+	}
+	rates, err := c.exchangeRatesProvider.GetExchangeRates(ctx)
+	if err != nil {
+		return types.ExchangeRates{}, types.CryptoRates{}, fmt.Errorf("error during fetching exchange rates, err: %w", err)
+	}
+	cryptoRates, err := c.cryptoExchangeRatesProvider.GetCryptoExchangeRates(ctx)
+	if err != nil {
+		return types.ExchangeRates{}, types.CryptoRates{}, fmt.Errorf("error during fetching crypto exchange rates, err: %w", err)
+	}
+	return rates, cryptoRates, nil
+}
+
+func (c *Converter) exchange(
+	rates types.ExchangeRates,
+	cryptoRates types.CryptoRates,
+	from, to string,
+	amount decimal.Decimal,
+) (types.ExchangedCurrency, error) {
+	assetFrom, ok := findAsset(from, rates.Rates, cryptoRates.Rates)
 	if !ok {
-		return types.ExchangedCryptoCurrency{}, fmt.Errorf("currency: %s not found in crypto currency rates", from)
+		return types.ExchangedCurrency{}, &types.UnknownCurrencyError{Currency: from, Source: "fiat and crypto currency"}
 	}
-	currencyTo, ok := rates[to]
+	assetTo, ok := findAsset(to, rates.Rates, cryptoRates.Rates)
 	if !ok {
-		return types.ExchangedCryptoCurrency{}, fmt.Errorf("currency: %s not found in crypto currency rates", to)
+		return types.ExchangedCurrency{}, &types.UnknownCurrencyError{Currency: to, Source: "fiat and crypto currency"}
+	}
+	numerator := assetFrom.usdNumerator.Mul(assetTo.usdDenominator)
+	denominator := assetFrom.usdDenominator.Mul(assetTo.usdNumerator)
+
+	assetClass := fees.Fiat
+	if assetFrom.crypto || assetTo.crypto {
+		assetClass = fees.Crypto
+	}
+	charge := c.feeSchedule.Apply(from, to, assetClass, numerator.Div(denominator), amount.Mul(numerator).Div(denominator))
+	result, fee := charge.Gross, charge.Fee
+	if assetTo.rounded {
+		result, fee = result.Round(assetTo.decimalPlaces), fee.Round(assetTo.decimalPlaces)
+	}
+	netAmount, err := deductFee(to, amount, result, fee)
+	if err != nil {
+		return types.ExchangedCurrency{}, err
 	}
 
-	usd := amount.Mul(currencyFrom.RateToUSD)
-	result := usd.Div(currencyTo.RateToUSD)
-	result = result.Round(int32(currencyTo.DecimalPlaces))
+	return types.ExchangedCurrency{
+		From:      from,
+		To:        to,
+		Amount:    result,
+		Rate:      charge.AppliedRate,
+		MidRate:   charge.MidRate,
+		Fee:       fee,
+		NetAmount: netAmount,
+		Path:      conversionPath(from, to),
+		Source:    rates.Source,
+	}, nil
+}
+
+func deductFee(currency string, amount, result, fee decimal.Decimal) (decimal.Decimal, error) {
+	netAmount := result.Sub(fee)
+	if netAmount.IsNegative() {
+		return decimal.Decimal{}, &types.InvalidInputError{
+			Field:  "amount",
+			Value:  amount.String(),
+			Reason: fmt.Sprintf("amount does not cover the fee of %s %s", fee, currency),
+		}
+	}
+	return netAmount, nil
+}
 
-	return types.ExchangedCryptoCurrency{From: from, To: to, Amount: result}, nil
+// asset describes the USD value of one unit as usdNumerator/usdDenominator.
+type asset struct {
+	usdNumerator   decimal.Decimal
+	usdDenominator decimal.Decimal
+	decimalPlaces  int32
+	rounded        bool
+	crypto         bool
+}
+
+func findAsset(code string, rates map[string]decimal.Decimal, cryptoRates map[string]types.CryptoCurrencyInfo) (asset, bool) {
+	if rate, ok := rates[code]; ok && rate.IsPositive() {
+		a := asset{usdNumerator: decimal.NewFromInt(1), usdDenominator: rate}
+		if currency, ok := currencies.Lookup(code); ok {
+			a.decimalPlaces, a.rounded = int32(currency.MinorUnits), true
+		}
+		return a, true
+	}
+	if info, ok := cryptoRates[code]; ok && info.RateToUSD.IsPositive() {
+		return asset{
+			usdNumerator:   info.RateToUSD,
+			usdDenominator: decimal.NewFromInt(1),
+			decimalPlaces:  int32(info.DecimalPlaces),
+			rounded:        true,
+			crypto:         true,
+		}, true
+	}
+	return asset{}, false
+}
+
+func conversionPath(from, to string) []string {
+	if from == pivotCurrency || to == pivotCurrency {
+		return []string{from, to}
+	}
+	return []string{from, pivotCurrency, to}
 }
 
-func validateCurrencies(currencies []string, rates map[string]decimal.Decimal) error {
+func validateCurrencies(currencies []string, rates types.ExchangeRates) error {
 	for _, currency := range currencies {
-		_, ok := rates[currency]
+		_, ok := rates.Rates[currency]
 		if !ok {
-			return fmt.Errorf("currency: %s not found in openexchangerates.org rates", currency)
+			return &types.UnknownCurrencyError{Currency: currency, Source: rates.Source}
 		}
 	}
 	return nil
@@ -2,17 +2,21 @@ package currencyconverter
 
 import (
 	"context"
+	"errors"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	exchangeratesprovider "github.com/wojcikp/currency-converter/internal/exchange_rates_provider"
+	"github.com/wojcikp/currency-converter/internal/fees"
 	"github.com/wojcikp/currency-converter/internal/types"
 )
 
 func TestGetCurrenciesRates(t *testing.T) {
 	provider := exchangeratesprovider.NewExchangeRatesProviderMock()
-	converter := NewConverter(provider)
+	converter := NewConverter(provider, provider, fees.Schedule{})
 	ctx := context.Background()
 	cases := []struct {
 		name      string
@@ -29,8 +33,11 @@ func TestGetCurrenciesRates(t *testing.T) {
 			if err != nil {
 				t.Fatalf("TestGetCurrenciesRates error: %v", err)
 			}
-			if len(out) != tc.wantCount {
-				t.Fatalf("len=%d, want %d", len(out), tc.wantCount)
+			if len(out.Rates) != tc.wantCount {
+				t.Fatalf("len=%d, want %d", len(out.Rates), tc.wantCount)
+			}
+			if out.Source != exchangeratesprovider.MockSource {
+				t.Fatalf("source=%s, want %s", out.Source, exchangeratesprovider.MockSource)
 			}
 		})
 	}
@@ -76,3 +83,274 @@ func TestGetCurrencyPairsToExchange(t *testing.T) {
 		})
 	}
 }
+
+func TestExchangeCurrenciesPath(t *testing.T) {
+	provider := exchangeratesprovider.NewExchangeRatesProviderMock()
+	converter := NewConverter(provider, provider, fees.Schedule{})
+	cases := []struct {
+		name     string
+		from, to string
+		wantPath []string
+	}{
+		{"crypto to fiat through usd", "WBTC", "GBP", []string{"WBTC", "USD", "GBP"}},
+		{"fiat to fiat through usd", "EUR", "PLN", []string{"EUR", "USD", "PLN"}},
+		{"source is usd", "USD", "WBTC", []string{"USD", "WBTC"}},
+		{"target is usd", "USDT", "USD", []string{"USDT", "USD"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := converter.ExchangeCurrencies(context.Background(), tc.from, tc.to, decimal.NewFromInt(2))
+			if err != nil {
+				t.Fatalf("ExchangeCurrencies error: %v", err)
+			}
+			assert.Equal(t, tc.wantPath, out.Path)
+		})
+	}
+}
+
+func TestConverterCanceledContext(t *testing.T) {
+	provider := exchangeratesprovider.NewExchangeRatesProviderMock()
+	converter := NewConverter(provider, provider, fees.Schedule{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := converter.GetCurrenciesRates(ctx, []string{"USD", "EUR"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetCurrenciesRates err=%v, want context.Canceled", err)
+	}
+	if _, err := converter.ExchangeCurrencies(ctx, "WBTC", "EUR", decimal.NewFromInt(1)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ExchangeCurrencies err=%v, want context.Canceled", err)
+	}
+}
+
+func TestGetTimeSeriesSummary(t *testing.T) {
+	provider := exchangeratesprovider.NewExchangeRatesProviderMock()
+	converter := NewConverter(provider, provider, fees.Schedule{})
+
+	series, err := converter.GetTimeSeries(context.Background(), types.TimeSeriesRequest{
+		From:     "USD",
+		To:       "PLN",
+		Start:    time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		End:      time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC),
+		Interval: types.IntervalDay,
+	})
+	if err != nil {
+		t.Fatalf("GetTimeSeries error: %v", err)
+	}
+
+	if len(series.Points) != 31 {
+		t.Fatalf("points=%d, want 31", len(series.Points))
+	}
+	for i := 1; i < len(series.Points); i++ {
+		if series.Points[i-1].Date >= series.Points[i].Date {
+			t.Fatalf("points not ordered: %s before %s", series.Points[i-1].Date, series.Points[i].Date)
+		}
+	}
+	want := types.TimeSeriesSummary{
+		Min:           decimal.RequireFromString("3.61"),
+		Max:           decimal.RequireFromString("3.91"),
+		Mean:          decimal.RequireFromString("3.76"),
+		Change:        decimal.RequireFromString("0.3"),
+		ChangePercent: decimal.RequireFromString("8.31024930747922"),
+	}
+	assert.True(t, want.Min.Equal(series.Summary.Min), "min=%s", series.Summary.Min)
+	assert.True(t, want.Max.Equal(series.Summary.Max), "max=%s", series.Summary.Max)
+	assert.True(t, want.Mean.Equal(series.Summary.Mean), "mean=%s", series.Summary.Mean)
+	assert.True(t, want.Change.Equal(series.Summary.Change), "change=%s", series.Summary.Change)
+	assert.True(t, want.ChangePercent.Equal(series.Summary.ChangePercent), "change_percent=%s", series.Summary.ChangePercent)
+}
+
+type countingHistoricalProvider struct {
+	*exchangeratesprovider.ExchangeRatesProviderMock
+	historicalCalls atomic.Int32
+}
+
+func (p *countingHistoricalProvider) GetHistoricalExchangeRates(ctx context.Context, date time.Time) (types.ExchangeRates, error) {
+	p.historicalCalls.Add(1)
+	return p.ExchangeRatesProviderMock.GetHistoricalExchangeRates(ctx, date)
+}
+
+func TestGetTimeSeriesDates(t *testing.T) {
+	provider := exchangeratesprovider.NewExchangeRatesProviderMock()
+	converter := NewConverter(provider, provider, fees.Schedule{})
+	day := func(month time.Month, day int) time.Time { return time.Date(2025, month, day, 0, 0, 0, 0, time.UTC) }
+
+	cases := []struct {
+		name       string
+		start, end time.Time
+		interval   string
+		wantDates  []string
+	}{
+		{"week", day(time.January, 1), day(time.January, 20), types.IntervalWeek, []string{"2025-01-01", "2025-01-08", "2025-01-15"}},
+		{"month", day(time.January, 15), day(time.April, 15), types.IntervalMonth, []string{"2025-01-15", "2025-02-15", "2025-03-15", "2025-04-15"}},
+		{"month from the 31st", day(time.January, 31), day(time.April, 30), types.IntervalMonth, []string{"2025-01-31", "2025-02-28", "2025-03-31", "2025-04-30"}},
+		{"month from the 29th", day(time.January, 29), day(time.March, 29), types.IntervalMonth, []string{"2025-01-29", "2025-02-28", "2025-03-29"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			series, err := converter.GetTimeSeries(context.Background(), types.TimeSeriesRequest{
+				From: "USD", To: "PLN", Start: tc.start, End: tc.end, Interval: tc.interval,
+			})
+			if err != nil {
+				t.Fatalf("GetTimeSeries error: %v", err)
+			}
+			var dates []string
+			for _, point := range series.Points {
+				dates = append(dates, point.Date)
+			}
+			assert.Equal(t, tc.wantDates, dates)
+		})
+	}
+}
+
+func TestGetTimeSeriesUnknownCurrency(t *testing.T) {
+	provider := &countingHistoricalProvider{ExchangeRatesProviderMock: exchangeratesprovider.NewExchangeRatesProviderMock()}
+	converter := NewConverter(provider, provider, fees.Schedule{})
+
+	_, err := converter.GetTimeSeries(context.Background(), types.TimeSeriesRequest{
+		From:     "USD",
+		To:       "XYZ",
+		Start:    time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		End:      time.Date(2025, time.December, 31, 0, 0, 0, 0, time.UTC),
+		Interval: types.IntervalDay,
+	})
+	var unknownErr *types.UnknownCurrencyError
+	if !errors.As(err, &unknownErr) {
+		t.Fatalf("err=%v, want unknown currency error", err)
+	}
+	if calls := provider.historicalCalls.Load(); calls != 0 {
+		t.Fatalf("upstream historical calls=%d, want 0 for an unknown currency", calls)
+	}
+}
+
+func TestConversionsWithFees(t *testing.T) {
+	provider := exchangeratesprovider.NewExchangeRatesProviderMock()
+	converter := NewConverter(provider, provider, fees.Schedule{
+		DefaultSpreadPercent:    decimal.RequireFromString("1"),
+		AssetClassSpreadPercent: map[fees.AssetClass]decimal.Decimal{fees.Crypto: decimal.RequireFromString("2")},
+		FixedFees:               map[string]decimal.Decimal{"PLN": decimal.RequireFromString("1.50")},
+		MinimumFees:             map[string]decimal.Decimal{"PLN": decimal.RequireFromString("5")},
+	})
+	ctx := context.Background()
+
+	converted, err := converter.ConvertCurrencies(ctx, "USD", "PLN", decimal.NewFromInt(100))
+	if err != nil {
+		t.Fatalf("ConvertCurrencies error: %v", err)
+	}
+	assert.True(t, converted.MidRate.Equal(decimal.RequireFromString("3.6201")), "mid_rate=%s", converted.MidRate)
+	assert.True(t, converted.Rate.Equal(decimal.RequireFromString("3.583899")), "rate=%s", converted.Rate)
+	assert.True(t, converted.Result.Equal(decimal.RequireFromString("358.39")), "result=%s", converted.Result)
+	// spread cost 3.62 plus fixed fee 1.50 already exceeds the minimum fee
+	assert.True(t, converted.Fee.Equal(decimal.RequireFromString("1.50")), "fee=%s", converted.Fee)
+	assert.True(t, converted.NetAmount.Equal(decimal.RequireFromString("356.89")), "net_amount=%s", converted.NetAmount)
+
+	exchanged, err := converter.ExchangeCurrencies(ctx, "WBTC", "USD", decimal.NewFromInt(1))
+	if err != nil {
+		t.Fatalf("ExchangeCurrencies error: %v", err)
+	}
+	assert.True(t, exchanged.Rate.Equal(decimal.RequireFromString("55896.4756")), "rate=%s", exchanged.Rate)
+	assert.True(t, exchanged.NetAmount.Equal(decimal.RequireFromString("55896.48")), "net_amount=%s", exchanged.NetAmount)
+
+	_, err = converter.ConvertCurrencies(ctx, "USD", "PLN", decimal.RequireFromString("0.10"))
+	var invalidInput *types.InvalidInputError
+	if !errors.As(err, &invalidInput) || invalidInput.Field != "amount" {
+		t.Fatalf("err=%v, want *types.InvalidInputError for amount below the minimum fee", err)
+	}
+}
+
+// shiftingProvider moves the PLN rate on every fetch, so conversions computed
+// from different snapshots disagree.
+type shiftingProvider struct {
+	*exchangeratesprovider.ExchangeRatesProviderMock
+	calls int
+}
+
+func (p *shiftingProvider) GetExchangeRates(ctx context.Context) (types.ExchangeRates, error) {
+	p.calls++
+	rates, err := p.ExchangeRatesProviderMock.GetExchangeRates(ctx)
+	if err != nil {
+		return types.ExchangeRates{}, err
+	}
+	rates.Rates["PLN"] = rates.Rates["PLN"].Add(decimal.NewFromInt(int64(p.calls)))
+	return rates, nil
+}
+
+func TestExchangeCurrenciesBatch(t *testing.T) {
+	provider := &shiftingProvider{ExchangeRatesProviderMock: exchangeratesprovider.NewExchangeRatesProviderMock()}
+	converter := NewConverter(provider, provider, fees.Schedule{})
+
+	batch, err := converter.ExchangeCurrenciesBatch(context.Background(), []types.BatchConversionItem{
+		{ID: "a", From: "USD", To: "PLN", Amount: decimal.NewFromInt(10)},
+		{ID: "b", From: "USD", To: "XYZ", Amount: decimal.NewFromInt(10)},
+		{ID: "c", From: "USD", To: "PLN", Amount: decimal.NewFromInt(10)},
+	})
+	if err != nil {
+		t.Fatalf("ExchangeCurrenciesBatch error: %v", err)
+	}
+	if provider.calls != 1 {
+		t.Fatalf("rates fetched %d times, want a single snapshot", provider.calls)
+	}
+	if len(batch.Conversions) != 3 {
+		t.Fatalf("conversions=%d, want 3", len(batch.Conversions))
+	}
+	first, third := batch.Conversions[0], batch.Conversions[2]
+	if first.Err != nil || third.Err != nil || !first.Result.Amount.Equal(third.Result.Amount) {
+		t.Fatalf("conversions %+v and %+v, want equal results from one snapshot", first, third)
+	}
+	var unknownCurrency *types.UnknownCurrencyError
+	if !errors.As(batch.Conversions[1].Err, &unknownCurrency) {
+		t.Fatalf("err=%v, want *types.UnknownCurrencyError", batch.Conversions[1].Err)
+	}
+}
+
+// snapshotProvider serves crypto rates refreshed after the fiat ones, and
+// fails the separate reads so that only GetSnapshot can serve a conversion.
+type snapshotProvider struct {
+	*exchangeratesprovider.ExchangeRatesProviderMock
+	snapshots int
+}
+
+func (p *snapshotProvider) GetExchangeRates(ctx context.Context) (types.ExchangeRates, error) {
+	return types.ExchangeRates{}, errors.New("read fiat rates separately")
+}
+
+func (p *snapshotProvider) GetCryptoExchangeRates(ctx context.Context) (types.CryptoRates, error) {
+	return types.CryptoRates{}, errors.New("read crypto rates separately")
+}
+
+func (p *snapshotProvider) GetSnapshot(ctx context.Context) (types.ExchangeRates, types.CryptoRates, error) {
+	p.snapshots++
+	rates, _ := p.ExchangeRatesProviderMock.GetExchangeRates(ctx)
+	cryptoRates, _ := p.ExchangeRatesProviderMock.GetCryptoExchangeRates(ctx)
+	cryptoRates.Timestamp = cryptoRates.Timestamp.Add(time.Minute)
+	return rates, cryptoRates, nil
+}
+
+func TestExchangeCurrenciesBatchSnapshot(t *testing.T) {
+	provider := &snapshotProvider{ExchangeRatesProviderMock: exchangeratesprovider.NewExchangeRatesProviderMock()}
+	converter := NewConverter(provider, provider, fees.Schedule{}, WithSnapshotProvider(provider))
+
+	batch, err := converter.ExchangeCurrenciesBatch(context.Background(), []types.BatchConversionItem{
+		{ID: "a", From: "USDT", To: "PLN", Amount: decimal.NewFromInt(10)},
+	})
+	if err != nil {
+		t.Fatalf("ExchangeCurrenciesBatch error: %v", err)
+	}
+	if provider.snapshots != 1 {
+		t.Fatalf("snapshots=%d, want 1", provider.snapshots)
+	}
+	if batch.Conversions[0].Err != nil {
+		t.Fatalf("conversion error: %v", batch.Conversions[0].Err)
+	}
+	if !batch.Timestamp.Equal(exchangeratesprovider.MockRatesTimestamp) {
+		t.Errorf("timestamp=%s, want %s", batch.Timestamp, exchangeratesprovider.MockRatesTimestamp)
+	}
+	if want := exchangeratesprovider.MockRatesTimestamp.Add(time.Minute); !batch.CryptoTimestamp.Equal(want) {
+		t.Errorf("crypto timestamp=%s, want %s", batch.CryptoTimestamp, want)
+	}
+
+	// without the option fiat and crypto rates are read from their providers
+	separate := NewConverter(provider, provider, fees.Schedule{})
+	if _, err := separate.ExchangeCurrenciesBatch(context.Background(), nil); err == nil {
+		t.Fatal("expected fiat and crypto rates to be read separately")
+	}
+}
@@ -0,0 +1,72 @@
+package currencyconverter
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/shopspring/decimal"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+// GetRatesMatrix returns the cross rates of currencies, rows and columns keep
+// the given order. When base is set, the rates from base to every column are
+// added as a separate row.
+func (c *Converter) GetRatesMatrix(ctx context.Context, currencies []string, base string) (types.RatesMatrix, error) {
+	rates, err := c.exchangeRatesProvider.GetExchangeRates(ctx)
+	if err != nil {
+		return types.RatesMatrix{}, fmt.Errorf("error during fetching exchange rates, err: %w", err)
+	}
+	if err := validateCurrencies(currencies, rates); err != nil {
+		return types.RatesMatrix{}, err
+	}
+
+	matrix := types.RatesMatrix{
+		Source:    rates.Source,
+		Timestamp: rates.Timestamp,
+		Rows:      currencies,
+		Columns:   currencies,
+		Values:    make([][]decimal.Decimal, len(currencies)),
+	}
+	for i, from := range currencies {
+		matrix.Values[i] = matrixRow(from, currencies, rates)
+	}
+
+	if base != "" {
+		if err := validateCurrencies([]string{base}, rates); err != nil {
+			return types.RatesMatrix{}, err
+		}
+		matrix.Base = &types.RatesMatrixRow{Currency: base, Values: matrixRow(base, currencies, rates)}
+	}
+	return matrix, nil
+}
+
+func matrixRow(from string, columns []string, rates types.ExchangeRates) []decimal.Decimal {
+	row := make([]decimal.Decimal, len(columns))
+	for j, to := range columns {
+		row[j] = rates.Rates[to].Div(rates.Rates[from])
+	}
+	return row
+}
+
+// GetLatestRates rebases the snapshot to base. An empty symbols list returns
+// every currency of the snapshot.
+func (c *Converter) GetLatestRates(ctx context.Context, base string, symbols []string) (types.LatestRates, error) {
+	rates, err := c.exchangeRatesProvider.GetExchangeRates(ctx)
+	if err != nil {
+		return types.LatestRates{}, fmt.Errorf("error during fetching exchange rates, err: %w", err)
+	}
+	if err := validateCurrencies(append([]string{base}, symbols...), rates); err != nil {
+		return types.LatestRates{}, err
+	}
+
+	if len(symbols) == 0 {
+		symbols = slices.Collect(maps.Keys(rates.Rates))
+	}
+	rebased := make(map[string]decimal.Decimal, len(symbols))
+	for _, symbol := range symbols {
+		rebased[symbol] = rates.Rates[symbol].Div(rates.Rates[base])
+	}
+	return types.LatestRates{Source: rates.Source, Timestamp: rates.Timestamp.Unix(), Base: base, Rates: rebased}, nil
+}
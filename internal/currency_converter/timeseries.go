@@ -0,0 +1,162 @@
+package currencyconverter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+const (
+	maxTimeSeriesPoints = 366
+	timeSeriesWorkers   = 8
+)
+
+func (c *Converter) GetTimeSeries(ctx context.Context, request types.TimeSeriesRequest) (types.TimeSeries, error) {
+	dates, err := timeSeriesDates(request.Start, request.End, request.Interval)
+	if err != nil {
+		return types.TimeSeries{}, err
+	}
+	// reject unknown currencies before fetching a snapshot for every date
+	latest, err := c.exchangeRatesProvider.GetExchangeRates(ctx)
+	if err != nil {
+		return types.TimeSeries{}, fmt.Errorf("error during fetching exchange rates, err: %w", err)
+	}
+	if err := validateCurrencies([]string{request.From, request.To}, latest); err != nil {
+		return types.TimeSeries{}, err
+	}
+
+	snapshots, err := c.fetchHistoricalRates(ctx, dates)
+	if err != nil {
+		return types.TimeSeries{}, err
+	}
+
+	points := make([]types.TimeSeriesPoint, len(dates))
+	for i, snapshot := range snapshots {
+		if err := validateCurrencies([]string{request.From, request.To}, snapshot); err != nil {
+			return types.TimeSeries{}, err
+		}
+		points[i] = types.TimeSeriesPoint{
+			Date: dates[i].Format(time.DateOnly),
+			Rate: snapshot.Rates[request.To].Div(snapshot.Rates[request.From]),
+		}
+	}
+
+	return types.TimeSeries{
+		From:     request.From,
+		To:       request.To,
+		Start:    request.Start.Format(time.DateOnly),
+		End:      request.End.Format(time.DateOnly),
+		Interval: request.Interval,
+		Points:   points,
+		Summary:  summarizeTimeSeries(points),
+	}, nil
+}
+
+func timeSeriesDates(start, end time.Time, interval string) ([]time.Time, error) {
+	if end.Before(start) {
+		return nil, &types.InvalidInputError{Field: "end", Value: end.Format(time.DateOnly), Reason: "end date must not be before start date"}
+	}
+	var step func(i int) time.Time
+	switch interval {
+	case types.IntervalDay:
+		step = func(i int) time.Time { return start.AddDate(0, 0, i) }
+	case types.IntervalWeek:
+		step = func(i int) time.Time { return start.AddDate(0, 0, 7*i) }
+	case types.IntervalMonth:
+		step = func(i int) time.Time { return addMonths(start, i) }
+	default:
+		return nil, &types.InvalidInputError{Field: "interval", Value: interval, Reason: "expected one of: day, week, month"}
+	}
+
+	var dates []time.Time
+	for i := 0; !step(i).After(end); i++ {
+		if len(dates) == maxTimeSeriesPoints {
+			return nil, &types.InvalidInputError{
+				Field:  "end",
+				Value:  end.Format(time.DateOnly),
+				Reason: fmt.Sprintf("range exceeds %d points, use a shorter range or a longer interval", maxTimeSeriesPoints),
+			}
+		}
+		dates = append(dates, step(i))
+	}
+	return dates, nil
+}
+
+// addMonths moves t by months, clamping the day to the end of shorter months
+// instead of overflowing into the next one as time.AddDate does.
+func addMonths(t time.Time, months int) time.Time {
+	firstOfMonth := time.Date(t.Year(), t.Month()+time.Month(months), 1, 0, 0, 0, 0, t.Location())
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	return time.Date(firstOfMonth.Year(), firstOfMonth.Month(), min(t.Day(), lastDay), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// fetchHistoricalRates fetches snapshots for all dates with a bounded pool of
+// workers and stops scheduling new dates after the first failure.
+func (c *Converter) fetchHistoricalRates(ctx context.Context, dates []time.Time) ([]types.ExchangeRates, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	snapshots := make([]types.ExchangeRates, len(dates))
+	jobs := make(chan int)
+	var firstErr error
+	var errOnce sync.Once
+	var wg sync.WaitGroup
+
+	for w := 0; w < min(timeSeriesWorkers, len(dates)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				rates, err := c.exchangeRatesProvider.GetHistoricalExchangeRates(ctx, dates[i])
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = fmt.Errorf("error during fetching historical exchange rates for %s, err: %w", dates[i].Format(time.DateOnly), err)
+						cancel()
+					})
+					continue
+				}
+				snapshots[i] = rates
+			}
+		}()
+	}
+
+dispatch:
+	for i := range dates {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+func summarizeTimeSeries(points []types.TimeSeriesPoint) types.TimeSeriesSummary {
+	first, last := points[0].Rate, points[len(points)-1].Rate
+	summary := types.TimeSeriesSummary{Min: first, Max: first, Change: last.Sub(first)}
+
+	sum := decimal.Zero
+	for _, point := range points {
+		summary.Min = decimal.Min(summary.Min, point.Rate)
+		summary.Max = decimal.Max(summary.Max, point.Rate)
+		sum = sum.Add(point.Rate)
+	}
+	summary.Mean = sum.Div(decimal.NewFromInt(int64(len(points))))
+	if !first.IsZero() {
+		summary.ChangePercent = summary.Change.Div(first).Mul(decimal.NewFromInt(100))
+	}
+	return summary
+}
@@ -0,0 +1,170 @@
+package ecbprovider
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+const (
+	providerName   = "ecb"
+	DefaultBaseURL = "https://www.ecb.europa.eu/stats/eurofxref"
+	DefaultTimeout = 10 * time.Second
+	dailyPath      = "eurofxref-daily.xml"
+	historyPath    = "eurofxref-hist-90d.xml"
+)
+
+// ECBRatesProvider serves the European Central Bank euro foreign exchange
+// reference rates, rebased from EUR to the USD quotes used across the app.
+type ECBRatesProvider struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+type Option func(*ECBRatesProvider)
+
+func WithBaseURL(baseURL string) Option {
+	return func(p *ECBRatesProvider) {
+		p.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *ECBRatesProvider) {
+		p.httpClient.Timeout = timeout
+	}
+}
+
+func WithTransport(transport http.RoundTripper) Option {
+	return func(p *ECBRatesProvider) {
+		p.httpClient.Transport = transport
+	}
+}
+
+type envelope struct {
+	Cube struct {
+		Days []day `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+type day struct {
+	Time  string `xml:"time,attr"`
+	Rates []struct {
+		Currency string          `xml:"currency,attr"`
+		Rate     decimal.Decimal `xml:"rate,attr"`
+	} `xml:"Cube"`
+}
+
+func NewECBRatesProvider(opts ...Option) (*ECBRatesProvider, error) {
+	p := &ECBRatesProvider{
+		baseURL:    DefaultBaseURL,
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if _, err := url.ParseRequestURI(p.baseURL); err != nil {
+		return nil, fmt.Errorf("invalid ecb base url: %s, err: %w", p.baseURL, err)
+	}
+	return p, nil
+}
+
+func (p *ECBRatesProvider) GetExchangeRates(ctx context.Context) (types.ExchangeRates, error) {
+	days, err := p.fetchDays(ctx, dailyPath)
+	if err != nil {
+		return types.ExchangeRates{}, err
+	}
+	return rebaseToUSD(days[0])
+}
+
+// GetHistoricalExchangeRates serves dates from the 90 day history. ECB does
+// not publish on weekends and TARGET holidays, so the last reference day on or
+// before date is used.
+func (p *ECBRatesProvider) GetHistoricalExchangeRates(ctx context.Context, date time.Time) (types.ExchangeRates, error) {
+	days, err := p.fetchDays(ctx, historyPath)
+	if err != nil {
+		return types.ExchangeRates{}, err
+	}
+	wanted := date.Format(time.DateOnly)
+	// history is published newest first
+	for _, d := range days {
+		if d.Time <= wanted {
+			return rebaseToUSD(d)
+		}
+	}
+	return types.ExchangeRates{}, &types.ProviderError{
+		Provider: providerName,
+		Err:      fmt.Errorf("date %s is older than the 90 day ecb history, oldest day: %s", wanted, days[len(days)-1].Time),
+	}
+}
+
+func (p *ECBRatesProvider) fetchDays(ctx context.Context, path string) ([]day, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/%s", p.baseURL, path), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request err: %w", err)
+	}
+	req.Header.Set("Accept", "application/xml")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, &types.ProviderError{
+			Provider:    providerName,
+			Unavailable: true,
+			Err:         fmt.Errorf("error during ecb reference rates GET, err: %w", err),
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &types.ProviderError{
+			Provider:    providerName,
+			Unavailable: resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError,
+			Err:         fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body)),
+		}
+	}
+
+	var data envelope
+	if err := xml.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, &types.ProviderError{Provider: providerName, Err: fmt.Errorf("xml decoding error: %w", err)}
+	}
+	if len(data.Cube.Days) == 0 {
+		return nil, &types.ProviderError{Provider: providerName, Err: errors.New("no reference rates in ecb response")}
+	}
+	return data.Cube.Days, nil
+}
+
+// rebaseToUSD turns EUR based quotes (units per 1 EUR) into units per 1 USD.
+func rebaseToUSD(d day) (types.ExchangeRates, error) {
+	date, err := time.Parse(time.DateOnly, d.Time)
+	if err != nil {
+		return types.ExchangeRates{}, &types.ProviderError{Provider: providerName, Err: fmt.Errorf("invalid reference day: %s", d.Time)}
+	}
+
+	eurRates := make(map[string]decimal.Decimal, len(d.Rates))
+	for _, rate := range d.Rates {
+		eurRates[rate.Currency] = rate.Rate
+	}
+	usdPerEUR, ok := eurRates["USD"]
+	if !ok || !usdPerEUR.IsPositive() {
+		return types.ExchangeRates{}, &types.ProviderError{Provider: providerName, Err: fmt.Errorf("no USD reference rate for %s", d.Time)}
+	}
+
+	rates := make(map[string]decimal.Decimal, len(eurRates)+1)
+	for currency, rate := range eurRates {
+		rates[currency] = rate.Div(usdPerEUR)
+	}
+	rates["EUR"] = decimal.NewFromInt(1).Div(usdPerEUR)
+	rates["USD"] = decimal.NewFromInt(1)
+
+	return types.ExchangeRates{Source: providerName, Timestamp: date, Rates: rates}, nil
+}
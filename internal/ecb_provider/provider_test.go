@@ -0,0 +1,127 @@
+package ecbprovider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+func newFixtureServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGetExchangeRates(t *testing.T) {
+	provider, err := NewECBRatesProvider(WithBaseURL(newFixtureServer(t).URL))
+	if err != nil {
+		t.Fatalf("NewECBRatesProvider error: %v", err)
+	}
+
+	rates, err := provider.GetExchangeRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetExchangeRates error: %v", err)
+	}
+	if rates.Source != providerName {
+		t.Errorf("source=%s, want %s", rates.Source, providerName)
+	}
+	if want := time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC); !rates.Timestamp.Equal(want) {
+		t.Errorf("timestamp=%s, want %s", rates.Timestamp, want)
+	}
+	if len(rates.Rates) != 31 {
+		t.Errorf("rates=%d, want 30 quoted currencies plus EUR", len(rates.Rates))
+	}
+
+	want := map[string]string{
+		"USD": "1",
+		"EUR": "0.8532423208191126",
+		"PLN": "3.6197098976109215",
+		"JPY": "144.3430034129692833",
+	}
+	for currency, rate := range want {
+		if !rates.Rates[currency].Equal(decimal.RequireFromString(rate)) {
+			t.Errorf("%s=%s, want %s", currency, rates.Rates[currency], rate)
+		}
+	}
+}
+
+func TestGetHistoricalExchangeRates(t *testing.T) {
+	provider, err := NewECBRatesProvider(WithBaseURL(newFixtureServer(t).URL))
+	if err != nil {
+		t.Fatalf("NewECBRatesProvider error: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		date    time.Time
+		wantDay time.Time
+		wantPLN string
+	}{
+		{"reference day", time.Date(2025, time.June, 27, 0, 0, 0, 0, time.UTC), time.Date(2025, time.June, 27, 0, 0, 0, 0, time.UTC), "3.6305220883534137"},
+		{"weekend uses previous reference day", time.Date(2025, time.June, 29, 0, 0, 0, 0, time.UTC), time.Date(2025, time.June, 27, 0, 0, 0, 0, time.UTC), "3.6305220883534137"},
+		{"latest day", time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC), "3.6197098976109215"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rates, err := provider.GetHistoricalExchangeRates(context.Background(), tc.date)
+			if err != nil {
+				t.Fatalf("GetHistoricalExchangeRates error: %v", err)
+			}
+			if !rates.Timestamp.Equal(tc.wantDay) {
+				t.Errorf("timestamp=%s, want %s", rates.Timestamp, tc.wantDay)
+			}
+			if !rates.Rates["PLN"].Equal(decimal.RequireFromString(tc.wantPLN)) {
+				t.Errorf("PLN=%s, want %s", rates.Rates["PLN"], tc.wantPLN)
+			}
+		})
+	}
+
+	_, err = provider.GetHistoricalExchangeRates(context.Background(), time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC))
+	var providerErr *types.ProviderError
+	if !errors.As(err, &providerErr) {
+		t.Fatalf("err=%v, want *types.ProviderError for date outside history", err)
+	}
+}
+
+func TestGetExchangeRatesErrors(t *testing.T) {
+	cases := []struct {
+		name            string
+		status          int
+		body            string
+		wantUnavailable bool
+	}{
+		{"server error", http.StatusInternalServerError, "", true},
+		{"not found", http.StatusNotFound, "", false},
+		{"malformed xml", http.StatusOK, "<gesmes:Envelope><Cube>", false},
+		{"no usd rate", http.StatusOK, `<Envelope><Cube><Cube time="2025-06-30"><Cube currency="PLN" rate="4.2423"/></Cube></Cube></Envelope>`, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			provider, err := NewECBRatesProvider(WithBaseURL(server.URL))
+			if err != nil {
+				t.Fatalf("NewECBRatesProvider error: %v", err)
+			}
+			_, err = provider.GetExchangeRates(context.Background())
+			var providerErr *types.ProviderError
+			if !errors.As(err, &providerErr) {
+				t.Fatalf("err=%v, want *types.ProviderError", err)
+			}
+			if providerErr.Unavailable != tc.wantUnavailable {
+				t.Fatalf("unavailable=%t, want %t", providerErr.Unavailable, tc.wantUnavailable)
+			}
+		})
+	}
+}
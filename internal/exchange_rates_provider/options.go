@@ -0,0 +1,41 @@
+package exchangeratesprovider
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultBaseURL   = "https://openexchangerates.org/api"
+	DefaultTimeout   = 10 * time.Second
+	DefaultUserAgent = "currency-converter"
+)
+
+type Option func(*ExchangeRatesProvider)
+
+func WithBaseURL(baseURL string) Option {
+	return func(p *ExchangeRatesProvider) {
+		p.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *ExchangeRatesProvider) {
+		p.httpClient.Timeout = timeout
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(p *ExchangeRatesProvider) {
+		p.userAgent = userAgent
+	}
+}
+
+// WithTransport replaces the http.RoundTripper used for upstream calls, e.g. to
+// route through a proxy or to replay recorded fixtures.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(p *ExchangeRatesProvider) {
+		p.httpClient.Transport = transport
+	}
+}
@@ -6,85 +6,137 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/shopspring/decimal"
 	"github.com/wojcikp/currency-converter/internal/types"
 )
 
+const providerName = "openexchangerates.org"
+
 type ExchangeRatesProvider struct {
 	openexchangeAppId string
+	baseURL           string
+	userAgent         string
 	httpClient        *http.Client
 }
 type ExchangeRatesProviderMock struct{}
 
+const MockSource = "mock"
+
+var MockRatesTimestamp = time.Date(2025, time.June, 30, 12, 0, 0, 0, time.UTC)
+
 type ExchangeRates struct {
-	Rates map[string]decimal.Decimal `json:"rates"`
+	Timestamp int64                      `json:"timestamp"`
+	Rates     map[string]decimal.Decimal `json:"rates"`
 }
 
-func NewExchangeRatesProvider(openexchangeAppId string) (*ExchangeRatesProvider, error) {
-	return &ExchangeRatesProvider{
+func NewExchangeRatesProvider(openexchangeAppId string, opts ...Option) (*ExchangeRatesProvider, error) {
+	p := &ExchangeRatesProvider{
 		openexchangeAppId: openexchangeAppId,
-		httpClient:        &http.Client{Timeout: 10 * time.Second},
-	}, nil
+		baseURL:           DefaultBaseURL,
+		userAgent:         DefaultUserAgent,
+		httpClient:        &http.Client{Timeout: DefaultTimeout},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if _, err := url.ParseRequestURI(p.baseURL); err != nil {
+		return nil, fmt.Errorf("invalid openexchangerates.org base url: %s, err: %w", p.baseURL, err)
+	}
+	return p, nil
+}
+
+func (p *ExchangeRatesProvider) GetExchangeRates(ctx context.Context) (types.ExchangeRates, error) {
+	return p.fetchRates(ctx, "latest.json")
+}
+
+func (p *ExchangeRatesProvider) GetHistoricalExchangeRates(ctx context.Context, date time.Time) (types.ExchangeRates, error) {
+	return p.fetchRates(ctx, fmt.Sprintf("historical/%s.json", date.Format(time.DateOnly)))
 }
 
-func (p *ExchangeRatesProvider) GetExchangeRates(ctx context.Context) (map[string]decimal.Decimal, error) {
-	url := fmt.Sprintf("https://openexchangerates.org/api/latest.json?app_id=%s", p.openexchangeAppId)
+func (p *ExchangeRatesProvider) fetchRates(ctx context.Context, path string) (types.ExchangeRates, error) {
+	ratesURL := fmt.Sprintf("%s/%s?app_id=%s", p.baseURL, path, url.QueryEscape(p.openexchangeAppId))
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ratesURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("creating request err: %w", err)
+		return types.ExchangeRates{}, fmt.Errorf("creating request err: %w", err)
 	}
 	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", p.userAgent)
 
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error during openexchangerates.org api GET, err: %w", err)
+		return types.ExchangeRates{}, &types.ProviderError{
+			Provider:    providerName,
+			Unavailable: true,
+			Err:         fmt.Errorf("error during openexchangerates.org api GET, err: %w", err),
+		}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		return types.ExchangeRates{}, &types.ProviderError{
+			Provider:    providerName,
+			Unavailable: resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError,
+			Err:         fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body)),
+		}
 	}
 
 	var data ExchangeRates
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, fmt.Errorf("json decoding error: %w", err)
+		return types.ExchangeRates{}, &types.ProviderError{Provider: providerName, Err: fmt.Errorf("json decoding error: %w", err)}
 	}
 
-	return data.Rates, nil
-}
-
-func (p *ExchangeRatesProvider) GetCryptoExchangeRates(ctx context.Context) map[string]types.CryptoCurrencyInfo {
-	return map[string]types.CryptoCurrencyInfo{
-		"BEER":  {DecimalPlaces: 18, RateToUSD: decimal.RequireFromString("0.00002461")},
-		"FLOKI": {DecimalPlaces: 18, RateToUSD: decimal.RequireFromString("0.0001428")},
-		"GATE":  {DecimalPlaces: 18, RateToUSD: decimal.RequireFromString("6.87")},
-		"USDT":  {DecimalPlaces: 6, RateToUSD: decimal.RequireFromString("0.999")},
-		"WBTC":  {DecimalPlaces: 8, RateToUSD: decimal.RequireFromString("57037.22")},
-	}
+	return types.ExchangeRates{
+		Source:    providerName,
+		Timestamp: time.Unix(data.Timestamp, 0).UTC(),
+		Rates:     data.Rates,
+	}, nil
 }
 
 func NewExchangeRatesProviderMock() *ExchangeRatesProviderMock {
 	return &ExchangeRatesProviderMock{}
 }
 
-func (p *ExchangeRatesProviderMock) GetExchangeRates(ctx context.Context) (map[string]decimal.Decimal, error) {
+func (p *ExchangeRatesProviderMock) GetExchangeRates(ctx context.Context) (types.ExchangeRates, error) {
+	if err := ctx.Err(); err != nil {
+		return types.ExchangeRates{}, err
+	}
 	rates := make(map[string]decimal.Decimal)
 	rates["EUR"] = decimal.NewFromFloat(0.861355)
 	rates["GBP"] = decimal.NewFromFloat(0.743283)
+	rates["JPY"] = decimal.NewFromFloat(147.1935)
+	rates["KWD"] = decimal.NewFromFloat(0.305721)
+	rates["PLN"] = decimal.NewFromFloat(3.6201)
 	rates["USD"] = decimal.NewFromInt(1)
+	return types.ExchangeRates{Source: MockSource, Timestamp: MockRatesTimestamp, Rates: rates}, nil
+}
+
+// GetHistoricalExchangeRates returns the latest mock rates with PLN shifted by
+// 0.01 per day of month, so that consecutive dates differ predictably.
+func (p *ExchangeRatesProviderMock) GetHistoricalExchangeRates(ctx context.Context, date time.Time) (types.ExchangeRates, error) {
+	rates, err := p.GetExchangeRates(ctx)
+	if err != nil {
+		return types.ExchangeRates{}, err
+	}
+	rates.Rates["PLN"] = decimal.RequireFromString("3.60").Add(decimal.New(int64(date.Day()), -2))
+	rates.Timestamp = time.Date(date.Year(), date.Month(), date.Day(), 23, 59, 59, 0, time.UTC)
 	return rates, nil
 }
 
-func (p *ExchangeRatesProviderMock) GetCryptoExchangeRates(ctx context.Context) map[string]types.CryptoCurrencyInfo {
-	return map[string]types.CryptoCurrencyInfo{
-		"BEER":  {DecimalPlaces: 18, RateToUSD: decimal.RequireFromString("0.00002461")},
-		"FLOKI": {DecimalPlaces: 18, RateToUSD: decimal.RequireFromString("0.0001428")},
-		"GATE":  {DecimalPlaces: 18, RateToUSD: decimal.RequireFromString("6.87")},
-		"USDT":  {DecimalPlaces: 6, RateToUSD: decimal.RequireFromString("0.999")},
-		"WBTC":  {DecimalPlaces: 8, RateToUSD: decimal.RequireFromString("57037.22")},
+func (p *ExchangeRatesProviderMock) GetCryptoExchangeRates(ctx context.Context) (types.CryptoRates, error) {
+	if err := ctx.Err(); err != nil {
+		return types.CryptoRates{}, err
+	}
+	rates := map[string]types.CryptoCurrencyInfo{
+		"BEER":  {Name: "Beercoin", DecimalPlaces: 18, RateToUSD: decimal.RequireFromString("0.00002461")},
+		"FLOKI": {Name: "FLOKI", DecimalPlaces: 18, RateToUSD: decimal.RequireFromString("0.0001428")},
+		"GATE":  {Name: "GateToken", DecimalPlaces: 18, RateToUSD: decimal.RequireFromString("6.87")},
+		"USDT":  {Name: "Tether", DecimalPlaces: 6, RateToUSD: decimal.RequireFromString("0.999")},
+		"WBTC":  {Name: "Wrapped Bitcoin", DecimalPlaces: 8, RateToUSD: decimal.RequireFromString("57037.22")},
 	}
+	return types.CryptoRates{Source: MockSource, Timestamp: MockRatesTimestamp, Rates: rates}, nil
 }
@@ -0,0 +1,184 @@
+package exchangeratesprovider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+const latestFixture = `{
+	"disclaimer": "Usage subject to terms: https://openexchangerates.org/terms",
+	"license": "https://openexchangerates.org/license",
+	"timestamp": 1751284800,
+	"base": "USD",
+	"rates": {"EUR": 0.851, "GBP": 0.7291, "PLN": 3.6201, "USD": 1}
+}`
+
+func TestGetExchangeRates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/latest.json" {
+			t.Errorf("path=%s, want /api/latest.json", r.URL.Path)
+		}
+		if appId := r.URL.Query().Get("app_id"); appId != "test-app-id" {
+			t.Errorf("app_id=%s, want test-app-id", appId)
+		}
+		if userAgent := r.Header.Get("User-Agent"); userAgent != "converter-tests" {
+			t.Errorf("User-Agent=%s, want converter-tests", userAgent)
+		}
+		w.Write([]byte(latestFixture))
+	}))
+	defer server.Close()
+
+	provider, err := NewExchangeRatesProvider(
+		"test-app-id",
+		WithBaseURL(server.URL+"/api/"),
+		WithUserAgent("converter-tests"),
+	)
+	if err != nil {
+		t.Fatalf("NewExchangeRatesProvider error: %v", err)
+	}
+
+	rates, err := provider.GetExchangeRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetExchangeRates error: %v", err)
+	}
+	if rates.Source != providerName {
+		t.Errorf("source=%s, want %s", rates.Source, providerName)
+	}
+	if want := time.Unix(1751284800, 0).UTC(); !rates.Timestamp.Equal(want) {
+		t.Errorf("timestamp=%s, want %s", rates.Timestamp, want)
+	}
+	if !rates.Rates["PLN"].Equal(decimal.RequireFromString("3.6201")) {
+		t.Errorf("PLN=%s, want 3.6201", rates.Rates["PLN"])
+	}
+}
+
+func TestGetHistoricalExchangeRates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/historical/2025-06-30.json" {
+			t.Errorf("path=%s, want /historical/2025-06-30.json", r.URL.Path)
+		}
+		w.Write([]byte(latestFixture))
+	}))
+	defer server.Close()
+
+	provider, err := NewExchangeRatesProvider("test-app-id", WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("NewExchangeRatesProvider error: %v", err)
+	}
+	date := time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC)
+	rates, err := provider.GetHistoricalExchangeRates(context.Background(), date)
+	if err != nil {
+		t.Fatalf("GetHistoricalExchangeRates error: %v", err)
+	}
+	if !rates.Rates["EUR"].Equal(decimal.RequireFromString("0.851")) {
+		t.Errorf("EUR=%s, want 0.851", rates.Rates["EUR"])
+	}
+}
+
+type countingTransport struct {
+	calls atomic.Int32
+	next  http.RoundTripper
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.calls.Add(1)
+	return t.next.RoundTrip(req)
+}
+
+func TestGetExchangeRatesWithTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(latestFixture))
+	}))
+	defer server.Close()
+
+	transport := &countingTransport{next: http.DefaultTransport}
+	provider, err := NewExchangeRatesProvider("test-app-id", WithBaseURL(server.URL), WithTransport(transport))
+	if err != nil {
+		t.Fatalf("NewExchangeRatesProvider error: %v", err)
+	}
+	if _, err := provider.GetExchangeRates(context.Background()); err != nil {
+		t.Fatalf("GetExchangeRates error: %v", err)
+	}
+	if calls := transport.calls.Load(); calls != 1 {
+		t.Fatalf("transport calls=%d, want 1", calls)
+	}
+}
+
+func TestGetExchangeRatesErrors(t *testing.T) {
+	cases := []struct {
+		name            string
+		handler         http.HandlerFunc
+		timeout         time.Duration
+		wantUnavailable bool
+	}{
+		{
+			name: "invalid app id",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error": true, "status": 401, "message": "invalid_app_id"}`))
+			},
+			wantUnavailable: false,
+		},
+		{
+			name: "quota exceeded",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			wantUnavailable: true,
+		},
+		{
+			name: "malformed body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"rates":`))
+			},
+			wantUnavailable: false,
+		},
+		{
+			name: "timeout",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(100 * time.Millisecond)
+				w.Write([]byte(latestFixture))
+			},
+			timeout:         10 * time.Millisecond,
+			wantUnavailable: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(tc.handler)
+			defer server.Close()
+
+			opts := []Option{WithBaseURL(server.URL)}
+			if tc.timeout > 0 {
+				opts = append(opts, WithTimeout(tc.timeout))
+			}
+			provider, err := NewExchangeRatesProvider("test-app-id", opts...)
+			if err != nil {
+				t.Fatalf("NewExchangeRatesProvider error: %v", err)
+			}
+
+			_, err = provider.GetExchangeRates(context.Background())
+			var providerErr *types.ProviderError
+			if !errors.As(err, &providerErr) {
+				t.Fatalf("err=%v, want *types.ProviderError", err)
+			}
+			if providerErr.Unavailable != tc.wantUnavailable {
+				t.Fatalf("unavailable=%t, want %t", providerErr.Unavailable, tc.wantUnavailable)
+			}
+		})
+	}
+}
+
+func TestNewExchangeRatesProviderInvalidBaseURL(t *testing.T) {
+	if _, err := NewExchangeRatesProvider("test-app-id", WithBaseURL("not a url")); err == nil {
+		t.Fatal("expected error for invalid base url")
+	}
+}
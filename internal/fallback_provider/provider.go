@@ -0,0 +1,89 @@
+package fallbackprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+const providerName = "fallback chain"
+
+type NamedProvider struct {
+	Name     string
+	Provider types.RatesProvider
+	Timeout  time.Duration
+}
+
+// FallbackRatesProvider asks providers in order and returns the first snapshot
+// that could be fetched within the provider's timeout.
+type FallbackRatesProvider struct {
+	providers []NamedProvider
+}
+
+func NewFallbackRatesProvider(providers ...NamedProvider) (*FallbackRatesProvider, error) {
+	if len(providers) == 0 {
+		return nil, errors.New("fallback chain needs at least one rates provider")
+	}
+	return &FallbackRatesProvider{providers}, nil
+}
+
+func (p *FallbackRatesProvider) GetExchangeRates(ctx context.Context) (types.ExchangeRates, error) {
+	return p.first(ctx, "latest", func(ctx context.Context, provider types.RatesProvider) (types.ExchangeRates, error) {
+		return provider.GetExchangeRates(ctx)
+	})
+}
+
+func (p *FallbackRatesProvider) GetHistoricalExchangeRates(ctx context.Context, date time.Time) (types.ExchangeRates, error) {
+	return p.first(ctx, "historical "+date.Format(time.DateOnly), func(ctx context.Context, provider types.RatesProvider) (types.ExchangeRates, error) {
+		return provider.GetHistoricalExchangeRates(ctx, date)
+	})
+}
+
+func (p *FallbackRatesProvider) first(
+	ctx context.Context,
+	description string,
+	fetch func(context.Context, types.RatesProvider) (types.ExchangeRates, error),
+) (types.ExchangeRates, error) {
+	var errs []error
+	for _, provider := range p.providers {
+		rates, err := p.fetch(ctx, provider, fetch)
+		if err == nil {
+			logrus.Infof("%s exchange rates served by %s", description, rates.Source)
+			return rates, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return types.ExchangeRates{}, ctxErr
+		}
+		logrus.Warnf("could not fetch %s exchange rates from %s, trying next provider. err: %v", description, provider.Name, err)
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name, err))
+	}
+	return types.ExchangeRates{}, &types.ProviderError{
+		Provider:    providerName,
+		Unavailable: true,
+		Err:         errors.Join(errs...),
+	}
+}
+
+func (p *FallbackRatesProvider) fetch(
+	ctx context.Context,
+	provider NamedProvider,
+	fetch func(context.Context, types.RatesProvider) (types.ExchangeRates, error),
+) (types.ExchangeRates, error) {
+	if provider.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, provider.Timeout)
+		defer cancel()
+	}
+	rates, err := fetch(ctx, provider.Provider)
+	if err != nil {
+		return types.ExchangeRates{}, err
+	}
+	if rates.Source == "" {
+		rates.Source = provider.Name
+	}
+	return rates, nil
+}
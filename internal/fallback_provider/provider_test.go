@@ -0,0 +1,128 @@
+package fallbackprovider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+type stubProvider struct {
+	source string
+	delay  time.Duration
+	err    error
+	calls  int
+}
+
+func (p *stubProvider) GetExchangeRates(ctx context.Context) (types.ExchangeRates, error) {
+	return p.GetHistoricalExchangeRates(ctx, time.Time{})
+}
+
+func (p *stubProvider) GetHistoricalExchangeRates(ctx context.Context, date time.Time) (types.ExchangeRates, error) {
+	p.calls++
+	select {
+	case <-time.After(p.delay):
+	case <-ctx.Done():
+		return types.ExchangeRates{}, ctx.Err()
+	}
+	if p.err != nil {
+		return types.ExchangeRates{}, p.err
+	}
+	return types.ExchangeRates{Source: p.source, Rates: map[string]decimal.Decimal{"USD": decimal.NewFromInt(1)}}, nil
+}
+
+func TestFallbackRatesProvider(t *testing.T) {
+	cases := []struct {
+		name       string
+		providers  []*stubProvider
+		wantSource string
+		wantCalls  []int
+	}{
+		{
+			name:       "first provider serves",
+			providers:  []*stubProvider{{source: "oxr"}, {source: "ecb"}},
+			wantSource: "oxr",
+			wantCalls:  []int{1, 0},
+		},
+		{
+			name:       "falls back on error",
+			providers:  []*stubProvider{{source: "oxr", err: errors.New("quota exceeded")}, {source: "ecb"}},
+			wantSource: "ecb",
+			wantCalls:  []int{1, 1},
+		},
+		{
+			name: "falls back on timeout",
+			providers: []*stubProvider{
+				{source: "oxr", delay: time.Second},
+				{source: "ecb", err: errors.New("down")},
+				{source: "file"},
+			},
+			wantSource: "file",
+			wantCalls:  []int{1, 1, 1},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var named []NamedProvider
+			for _, provider := range tc.providers {
+				named = append(named, NamedProvider{Name: provider.source, Provider: provider, Timeout: 20 * time.Millisecond})
+			}
+			chain, err := NewFallbackRatesProvider(named...)
+			if err != nil {
+				t.Fatalf("NewFallbackRatesProvider error: %v", err)
+			}
+
+			rates, err := chain.GetExchangeRates(context.Background())
+			if err != nil {
+				t.Fatalf("GetExchangeRates error: %v", err)
+			}
+			if rates.Source != tc.wantSource {
+				t.Fatalf("source=%s, want %s", rates.Source, tc.wantSource)
+			}
+			for i, provider := range tc.providers {
+				if provider.calls != tc.wantCalls[i] {
+					t.Fatalf("%s calls=%d, want %d", provider.source, provider.calls, tc.wantCalls[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFallbackRatesProviderAllFail(t *testing.T) {
+	chain, err := NewFallbackRatesProvider(
+		NamedProvider{Name: "oxr", Provider: &stubProvider{err: errors.New("quota exceeded")}},
+		NamedProvider{Name: "ecb", Provider: &stubProvider{err: errors.New("down")}},
+	)
+	if err != nil {
+		t.Fatalf("NewFallbackRatesProvider error: %v", err)
+	}
+
+	_, err = chain.GetHistoricalExchangeRates(context.Background(), time.Now())
+	var providerErr *types.ProviderError
+	if !errors.As(err, &providerErr) || !providerErr.Unavailable {
+		t.Fatalf("err=%v, want unavailable *types.ProviderError", err)
+	}
+}
+
+func TestFallbackRatesProviderCanceledContext(t *testing.T) {
+	second := &stubProvider{source: "ecb"}
+	chain, err := NewFallbackRatesProvider(
+		NamedProvider{Name: "oxr", Provider: &stubProvider{source: "oxr", delay: time.Second}},
+		NamedProvider{Name: "ecb", Provider: second},
+	)
+	if err != nil {
+		t.Fatalf("NewFallbackRatesProvider error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := chain.GetExchangeRates(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err=%v, want context.DeadlineExceeded", err)
+	}
+	if second.calls != 0 {
+		t.Fatalf("next provider called %d times after the caller gave up", second.calls)
+	}
+}
@@ -0,0 +1,142 @@
+package fees
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+type AssetClass string
+
+const (
+	Fiat   AssetClass = "fiat"
+	Crypto AssetClass = "crypto"
+)
+
+var hundred = decimal.NewFromInt(100)
+
+// Schedule holds the markup applied to conversions. Spreads are percentages
+// taken off the mid rate, the most specific one wins: pair, asset class,
+// default. Fixed and minimum fees are charged in the target currency. The zero
+// value charges nothing.
+type Schedule struct {
+	DefaultSpreadPercent    decimal.Decimal                `json:"default_spread_percent"`
+	AssetClassSpreadPercent map[AssetClass]decimal.Decimal `json:"asset_class_spread_percent"`
+	// PairSpreadPercent is keyed by FROM/TO, e.g. EUR/PLN.
+	PairSpreadPercent map[string]decimal.Decimal `json:"pair_spread_percent"`
+	FixedFees         map[string]decimal.Decimal `json:"fixed_fees"`
+	// MinimumFees bound the total charge, i.e. the spread cost plus the fixed fee.
+	MinimumFees map[string]decimal.Decimal `json:"minimum_fees"`
+}
+
+// Charge is the outcome of applying a schedule to a single conversion. Gross
+// and Fee are expressed in the target currency and are not rounded.
+type Charge struct {
+	MidRate     decimal.Decimal
+	AppliedRate decimal.Decimal
+	Gross       decimal.Decimal
+	Fee         decimal.Decimal
+}
+
+func LoadSchedule(path string) (Schedule, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("could not read fee schedule: %s, err: %w", path, err)
+	}
+	var schedule Schedule
+	if err := json.Unmarshal(content, &schedule); err != nil {
+		return Schedule{}, fmt.Errorf("could not parse fee schedule: %s, err: %w", path, err)
+	}
+	if err := schedule.normalize(); err != nil {
+		return Schedule{}, fmt.Errorf("invalid fee schedule: %s, err: %w", path, err)
+	}
+	return schedule, nil
+}
+
+// Apply charges a conversion of from into to. midAmount is the converted
+// amount at midRate, passed separately so that callers keep their precision.
+func (s Schedule) Apply(from, to string, class AssetClass, midRate, midAmount decimal.Decimal) Charge {
+	keep := decimal.NewFromInt(1).Sub(s.spreadPercent(from, to, class).Div(hundred))
+	gross := midAmount.Mul(keep)
+
+	fee := s.FixedFees[to]
+	if minimum, ok := s.MinimumFees[to]; ok {
+		if spreadCost := midAmount.Sub(gross); spreadCost.Add(fee).LessThan(minimum) {
+			fee = minimum.Sub(spreadCost)
+		}
+	}
+
+	return Charge{
+		MidRate:     midRate,
+		AppliedRate: midRate.Mul(keep),
+		Gross:       gross,
+		Fee:         fee,
+	}
+}
+
+func (s Schedule) spreadPercent(from, to string, class AssetClass) decimal.Decimal {
+	if spread, ok := s.PairSpreadPercent[from+"/"+to]; ok {
+		return spread
+	}
+	if spread, ok := s.AssetClassSpreadPercent[class]; ok {
+		return spread
+	}
+	return s.DefaultSpreadPercent
+}
+
+// normalize upper-cases currency codes and rejects values that would make a
+// conversion pay out more than the mid rate.
+func (s *Schedule) normalize() error {
+	if err := validateSpread("default", s.DefaultSpreadPercent); err != nil {
+		return err
+	}
+	for class, spread := range s.AssetClassSpreadPercent {
+		if class != Fiat && class != Crypto {
+			return fmt.Errorf("unknown asset class: %s, expected %s or %s", class, Fiat, Crypto)
+		}
+		if err := validateSpread(string(class), spread); err != nil {
+			return err
+		}
+	}
+
+	pairs := make(map[string]decimal.Decimal, len(s.PairSpreadPercent))
+	for pair, spread := range s.PairSpreadPercent {
+		currencies := strings.Split(strings.ToUpper(pair), "/")
+		if len(currencies) != 2 || currencies[0] == "" || currencies[1] == "" {
+			return fmt.Errorf("invalid pair: %s, expected FROM/TO", pair)
+		}
+		if err := validateSpread(pair, spread); err != nil {
+			return err
+		}
+		pairs[strings.Join(currencies, "/")] = spread
+	}
+	s.PairSpreadPercent = pairs
+
+	var err error
+	if s.FixedFees, err = normalizeFees("fixed", s.FixedFees); err != nil {
+		return err
+	}
+	s.MinimumFees, err = normalizeFees("minimum", s.MinimumFees)
+	return err
+}
+
+func validateSpread(name string, spread decimal.Decimal) error {
+	if spread.IsNegative() || spread.GreaterThanOrEqual(hundred) {
+		return fmt.Errorf("%s spread must be within [0, 100) percent, got: %s", name, spread)
+	}
+	return nil
+}
+
+func normalizeFees(kind string, fees map[string]decimal.Decimal) (map[string]decimal.Decimal, error) {
+	normalized := make(map[string]decimal.Decimal, len(fees))
+	for currency, fee := range fees {
+		if fee.IsNegative() {
+			return nil, fmt.Errorf("%s fee for %s must not be negative, got: %s", kind, currency, fee)
+		}
+		normalized[strings.ToUpper(currency)] = fee
+	}
+	return normalized, nil
+}
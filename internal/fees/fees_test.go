@@ -0,0 +1,110 @@
+package fees
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestApply(t *testing.T) {
+	d := decimal.RequireFromString
+	cases := []struct {
+		name            string
+		schedule        Schedule
+		class           AssetClass
+		wantAppliedRate string
+		wantGross       string
+		wantFee         string
+	}{
+		{"no fees", Schedule{}, Fiat, "4", "400", "0"},
+		{"default spread", Schedule{DefaultSpreadPercent: d("0.5")}, Fiat, "3.98", "398", "0"},
+		{
+			"asset class spread",
+			Schedule{DefaultSpreadPercent: d("0.5"), AssetClassSpreadPercent: map[AssetClass]decimal.Decimal{Crypto: d("2")}},
+			Crypto, "3.92", "392", "0",
+		},
+		{
+			"pair spread wins over asset class",
+			Schedule{AssetClassSpreadPercent: map[AssetClass]decimal.Decimal{Fiat: d("3")}, PairSpreadPercent: map[string]decimal.Decimal{"EUR/PLN": d("1")}},
+			Fiat, "3.96", "396", "0",
+		},
+		{"fixed fee", Schedule{FixedFees: map[string]decimal.Decimal{"PLN": d("2")}}, Fiat, "4", "400", "2"},
+		{
+			"minimum fee tops up spread cost",
+			Schedule{DefaultSpreadPercent: d("1"), FixedFees: map[string]decimal.Decimal{"PLN": d("2")}, MinimumFees: map[string]decimal.Decimal{"PLN": d("10")}},
+			Fiat, "3.96", "396", "6",
+		},
+		{
+			"minimum fee already covered",
+			Schedule{DefaultSpreadPercent: d("1"), FixedFees: map[string]decimal.Decimal{"PLN": d("2")}, MinimumFees: map[string]decimal.Decimal{"PLN": d("5")}},
+			Fiat, "3.96", "396", "2",
+		},
+		{"fees of other currency", Schedule{FixedFees: map[string]decimal.Decimal{"USD": d("2")}}, Fiat, "4", "400", "0"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			charge := tc.schedule.Apply("EUR", "PLN", tc.class, d("4"), d("400"))
+			if !charge.MidRate.Equal(d("4")) {
+				t.Errorf("mid_rate=%s, want 4", charge.MidRate)
+			}
+			if !charge.AppliedRate.Equal(d(tc.wantAppliedRate)) {
+				t.Errorf("applied_rate=%s, want %s", charge.AppliedRate, tc.wantAppliedRate)
+			}
+			if !charge.Gross.Equal(d(tc.wantGross)) {
+				t.Errorf("gross=%s, want %s", charge.Gross, tc.wantGross)
+			}
+			if !charge.Fee.Equal(d(tc.wantFee)) {
+				t.Errorf("fee=%s, want %s", charge.Fee, tc.wantFee)
+			}
+		})
+	}
+}
+
+func TestLoadSchedule(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"valid", `{"default_spread_percent":"0.5","asset_class_spread_percent":{"crypto":"1.5"},"pair_spread_percent":{"eur/pln":"0.2"},"fixed_fees":{"pln":"2.00"},"minimum_fees":{"pln":"5"}}`, false},
+		{"empty", `{}`, false},
+		{"spread of 100 percent", `{"default_spread_percent":"100"}`, true},
+		{"negative spread", `{"pair_spread_percent":{"EUR/PLN":"-1"}}`, true},
+		{"invalid pair", `{"pair_spread_percent":{"EURPLN":"1"}}`, true},
+		{"unknown asset class", `{"asset_class_spread_percent":{"stocks":"1"}}`, true},
+		{"negative fee", `{"fixed_fees":{"PLN":"-2"}}`, true},
+		{"malformed json", `{"fixed_fees":`, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "fees.json")
+			if err := os.WriteFile(path, []byte(tc.content), 0o600); err != nil {
+				t.Fatalf("writing schedule: %v", err)
+			}
+			schedule, err := LoadSchedule(path)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadSchedule error: %v", err)
+			}
+			if tc.name == "valid" {
+				if _, ok := schedule.PairSpreadPercent["EUR/PLN"]; !ok {
+					t.Errorf("pair spreads=%v, want upper-cased EUR/PLN key", schedule.PairSpreadPercent)
+				}
+				if _, ok := schedule.FixedFees["PLN"]; !ok {
+					t.Errorf("fixed fees=%v, want upper-cased PLN key", schedule.FixedFees)
+				}
+			}
+		})
+	}
+
+	if _, err := LoadSchedule(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+}
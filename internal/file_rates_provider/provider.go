@@ -0,0 +1,75 @@
+package fileratesprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+const providerName = "file"
+
+// FileRatesProvider serves a static snapshot stored in the openexchangerates.org
+// latest.json format. It is meant as the last resort of a fallback chain.
+type FileRatesProvider struct {
+	path string
+}
+
+type fileRates struct {
+	Timestamp int64                      `json:"timestamp"`
+	Base      string                     `json:"base"`
+	Rates     map[string]decimal.Decimal `json:"rates"`
+}
+
+func NewFileRatesProvider(path string) (*FileRatesProvider, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("could not access rates file: %s, err: %w", path, err)
+	}
+	return &FileRatesProvider{path}, nil
+}
+
+func (p *FileRatesProvider) GetExchangeRates(ctx context.Context) (types.ExchangeRates, error) {
+	if err := ctx.Err(); err != nil {
+		return types.ExchangeRates{}, err
+	}
+	content, err := os.ReadFile(p.path)
+	if err != nil {
+		return types.ExchangeRates{}, &types.ProviderError{Provider: providerName, Unavailable: true, Err: err}
+	}
+
+	var data fileRates
+	if err := json.Unmarshal(content, &data); err != nil {
+		return types.ExchangeRates{}, &types.ProviderError{Provider: providerName, Err: fmt.Errorf("json decoding error: %w", err)}
+	}
+	if data.Base != "" && data.Base != "USD" {
+		return types.ExchangeRates{}, &types.ProviderError{Provider: providerName, Err: fmt.Errorf("rates file base must be USD, got: %s", data.Base)}
+	}
+	if len(data.Rates) == 0 {
+		return types.ExchangeRates{}, &types.ProviderError{Provider: providerName, Err: errors.New("rates file contains no rates")}
+	}
+	// rates are divided by each other, a zero rate would fail every request
+	for currency, rate := range data.Rates {
+		if !rate.IsPositive() {
+			return types.ExchangeRates{}, &types.ProviderError{Provider: providerName, Err: fmt.Errorf("rates file contains non-positive rate %s for %s", rate, currency)}
+		}
+	}
+
+	return types.ExchangeRates{
+		Source:    providerName,
+		Timestamp: time.Unix(data.Timestamp, 0).UTC(),
+		Rates:     data.Rates,
+	}, nil
+}
+
+func (p *FileRatesProvider) GetHistoricalExchangeRates(ctx context.Context, date time.Time) (types.ExchangeRates, error) {
+	return types.ExchangeRates{}, &types.ProviderError{
+		Provider:    providerName,
+		Unavailable: true,
+		Err:         errors.New("static rates file does not provide historical rates"),
+	}
+}
@@ -0,0 +1,78 @@
+package fileratesprovider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+func writeRatesFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rates.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("could not write rates file: %v", err)
+	}
+	return path
+}
+
+func TestFileRatesProvider(t *testing.T) {
+	path := writeRatesFile(t, `{"timestamp": 1751284800, "base": "USD", "rates": {"USD": 1, "EUR": 0.851, "PLN": 3.6201}}`)
+	provider, err := NewFileRatesProvider(path)
+	if err != nil {
+		t.Fatalf("NewFileRatesProvider error: %v", err)
+	}
+
+	rates, err := provider.GetExchangeRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetExchangeRates error: %v", err)
+	}
+	if rates.Source != providerName {
+		t.Errorf("source=%s, want %s", rates.Source, providerName)
+	}
+	if want := time.Unix(1751284800, 0).UTC(); !rates.Timestamp.Equal(want) {
+		t.Errorf("timestamp=%s, want %s", rates.Timestamp, want)
+	}
+	if !rates.Rates["PLN"].Equal(decimal.RequireFromString("3.6201")) {
+		t.Errorf("PLN=%s, want 3.6201", rates.Rates["PLN"])
+	}
+
+	if _, err := provider.GetHistoricalExchangeRates(context.Background(), time.Now()); err == nil {
+		t.Error("expected error for historical rates from a static file")
+	}
+}
+
+func TestFileRatesProviderErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+	}{
+		{"malformed json", `{"rates": `},
+		{"not usd based", `{"base": "EUR", "rates": {"EUR": 1}}`},
+		{"no rates", `{"base": "USD", "rates": {}}`},
+		{"zero rate", `{"base": "USD", "rates": {"USD": 1, "EUR": 0}}`},
+		{"negative rate", `{"base": "USD", "rates": {"USD": 1, "PLN": -3.62}}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider, err := NewFileRatesProvider(writeRatesFile(t, tc.content))
+			if err != nil {
+				t.Fatalf("NewFileRatesProvider error: %v", err)
+			}
+			_, err = provider.GetExchangeRates(context.Background())
+			var providerErr *types.ProviderError
+			if !errors.As(err, &providerErr) {
+				t.Fatalf("err=%v, want *types.ProviderError", err)
+			}
+		})
+	}
+
+	if _, err := NewFileRatesProvider(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("expected error for missing rates file")
+	}
+}
@@ -0,0 +1,98 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+const (
+	StatusOK        = "ok"
+	StatusNotLoaded = "not_loaded"
+	StatusStale     = "stale"
+	StatusDown      = "down"
+	StatusDisabled  = "disabled"
+)
+
+const (
+	CheckFiatRates   = "fiat_rates"
+	CheckCryptoRates = "crypto_rates"
+	CheckStore       = "store"
+)
+
+const storePingTimeout = 2 * time.Second
+
+type Snapshots interface {
+	RefreshTimes() (fiat, crypto time.Time)
+}
+
+type Store interface {
+	Ping(ctx context.Context) error
+}
+
+// Checker reports the service ready once the fiat snapshot is loaded and while
+// its last successful refresh is within maxSnapshotAge. Age is not measured from
+// the snapshot timestamp, since daily publishers such as ECB and NBP leave it a
+// day or a weekend behind. The crypto snapshot is always reported, but affects
+// readiness only when requireCrypto is set, fiat endpoints keep working without
+// it. A nil store is reported as disabled and does not affect readiness.
+type Checker struct {
+	snapshots      Snapshots
+	store          Store
+	maxSnapshotAge time.Duration
+	requireCrypto  bool
+	now            func() time.Time
+}
+
+func NewChecker(snapshots Snapshots, store Store, maxSnapshotAge time.Duration, requireCrypto bool) *Checker {
+	return &Checker{snapshots, store, maxSnapshotAge, requireCrypto, time.Now}
+}
+
+func (c *Checker) CheckReadiness(ctx context.Context) types.Readiness {
+	fiat, crypto := c.snapshots.RefreshTimes()
+	now := c.now()
+	checks := map[string]types.DependencyStatus{
+		CheckFiatRates:   c.checkSnapshot(fiat, now),
+		CheckCryptoRates: c.checkSnapshot(crypto, now),
+		CheckStore:       c.checkStore(ctx),
+	}
+
+	ready := true
+	for name, check := range checks {
+		if name == CheckCryptoRates && !c.requireCrypto {
+			continue
+		}
+		if check.Status != StatusOK && check.Status != StatusDisabled {
+			ready = false
+		}
+	}
+	return types.Readiness{Ready: ready, Checks: checks}
+}
+
+func (c *Checker) checkSnapshot(refreshedAt, now time.Time) types.DependencyStatus {
+	if refreshedAt.IsZero() {
+		return types.DependencyStatus{Status: StatusNotLoaded, Error: "no rates snapshot loaded yet"}
+	}
+	age := now.Sub(refreshedAt)
+	ageSeconds := int64(age.Seconds())
+	status := types.DependencyStatus{Status: StatusOK, Timestamp: &refreshedAt, AgeSeconds: &ageSeconds}
+	if age > c.maxSnapshotAge {
+		status.Status = StatusStale
+		status.Error = fmt.Sprintf("rates snapshot was last refreshed %s ago, max allowed age is %s", age.Truncate(time.Second), c.maxSnapshotAge)
+	}
+	return status
+}
+
+func (c *Checker) checkStore(ctx context.Context) types.DependencyStatus {
+	if c.store == nil {
+		return types.DependencyStatus{Status: StatusDisabled}
+	}
+	ctx, cancel := context.WithTimeout(ctx, storePingTimeout)
+	defer cancel()
+	if err := c.store.Ping(ctx); err != nil {
+		return types.DependencyStatus{Status: StatusDown, Error: err.Error()}
+	}
+	return types.DependencyStatus{Status: StatusOK}
+}
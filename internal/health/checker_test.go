@@ -0,0 +1,131 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+type fakeSnapshots struct {
+	fiat, crypto time.Time
+}
+
+func (s fakeSnapshots) RefreshTimes() (time.Time, time.Time) {
+	return s.fiat, s.crypto
+}
+
+type fakeStore struct {
+	err error
+}
+
+func (s fakeStore) Ping(ctx context.Context) error {
+	return s.err
+}
+
+func TestCheckReadiness(t *testing.T) {
+	now := time.Date(2025, time.June, 30, 12, 0, 0, 0, time.UTC)
+	fresh := now.Add(-90 * time.Second)
+	old := now.Add(-2 * time.Hour)
+
+	cases := []struct {
+		name          string
+		snapshots     fakeSnapshots
+		store         Store
+		requireCrypto bool
+		wantReady     bool
+		wantStatus    map[string]string
+	}{
+		{
+			name:       "fresh snapshots without store",
+			snapshots:  fakeSnapshots{fiat: fresh, crypto: fresh},
+			wantReady:  true,
+			wantStatus: map[string]string{CheckFiatRates: StatusOK, CheckCryptoRates: StatusOK, CheckStore: StatusDisabled},
+		},
+		{
+			name:       "fresh snapshots with store",
+			snapshots:  fakeSnapshots{fiat: fresh, crypto: fresh},
+			store:      fakeStore{},
+			wantReady:  true,
+			wantStatus: map[string]string{CheckFiatRates: StatusOK, CheckCryptoRates: StatusOK, CheckStore: StatusOK},
+		},
+		{
+			name:       "fiat snapshot not loaded",
+			snapshots:  fakeSnapshots{crypto: fresh},
+			wantReady:  false,
+			wantStatus: map[string]string{CheckFiatRates: StatusNotLoaded, CheckCryptoRates: StatusOK, CheckStore: StatusDisabled},
+		},
+		{
+			name:       "crypto snapshot stale",
+			snapshots:  fakeSnapshots{fiat: fresh, crypto: old},
+			wantReady:  true,
+			wantStatus: map[string]string{CheckFiatRates: StatusOK, CheckCryptoRates: StatusStale, CheckStore: StatusDisabled},
+		},
+		{
+			name:          "crypto snapshot stale when required",
+			snapshots:     fakeSnapshots{fiat: fresh, crypto: old},
+			requireCrypto: true,
+			wantReady:     false,
+			wantStatus:    map[string]string{CheckFiatRates: StatusOK, CheckCryptoRates: StatusStale, CheckStore: StatusDisabled},
+		},
+		{
+			name:          "crypto snapshot not loaded when required",
+			snapshots:     fakeSnapshots{fiat: fresh},
+			requireCrypto: true,
+			wantReady:     false,
+			wantStatus:    map[string]string{CheckFiatRates: StatusOK, CheckCryptoRates: StatusNotLoaded, CheckStore: StatusDisabled},
+		},
+		{
+			name:       "fiat snapshot stale",
+			snapshots:  fakeSnapshots{fiat: old, crypto: fresh},
+			wantReady:  false,
+			wantStatus: map[string]string{CheckFiatRates: StatusStale, CheckCryptoRates: StatusOK, CheckStore: StatusDisabled},
+		},
+		{
+			name:       "store down",
+			snapshots:  fakeSnapshots{fiat: fresh, crypto: fresh},
+			store:      fakeStore{err: errors.New("database is closed")},
+			wantReady:  false,
+			wantStatus: map[string]string{CheckFiatRates: StatusOK, CheckCryptoRates: StatusOK, CheckStore: StatusDown},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker := NewChecker(tc.snapshots, tc.store, time.Hour, tc.requireCrypto)
+			checker.now = func() time.Time { return now }
+
+			readiness := checker.CheckReadiness(context.Background())
+			if readiness.Ready != tc.wantReady {
+				t.Errorf("ready=%v, want %v", readiness.Ready, tc.wantReady)
+			}
+			gotStatus := map[string]string{}
+			for name, check := range readiness.Checks {
+				gotStatus[name] = check.Status
+			}
+			if diff := cmp.Diff(tc.wantStatus, gotStatus); diff != "" {
+				t.Errorf("statuses mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCheckSnapshotDetails(t *testing.T) {
+	now := time.Date(2025, time.June, 30, 12, 0, 0, 0, time.UTC)
+	timestamp := now.Add(-2 * time.Hour)
+	checker := NewChecker(fakeSnapshots{fiat: timestamp}, nil, time.Hour, false)
+	checker.now = func() time.Time { return now }
+
+	ageSeconds := int64(7200)
+	want := types.DependencyStatus{
+		Status:     StatusStale,
+		Timestamp:  &timestamp,
+		AgeSeconds: &ageSeconds,
+		Error:      "rates snapshot was last refreshed 2h0m0s ago, max allowed age is 1h0m0s",
+	}
+	if diff := cmp.Diff(want, checker.CheckReadiness(context.Background()).Checks[CheckFiatRates]); diff != "" {
+		t.Errorf("fiat check mismatch (-want +got):\n%s", diff)
+	}
+}
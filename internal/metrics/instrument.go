@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+type instrumentedRatesProvider struct {
+	provider types.RatesProvider
+	name     string
+	metrics  *Metrics
+}
+
+// InstrumentRatesProvider counts calls, errors and latency of a single
+// upstream provider under the given name.
+func (m *Metrics) InstrumentRatesProvider(name string, provider types.RatesProvider) types.RatesProvider {
+	return &instrumentedRatesProvider{provider, name, m}
+}
+
+func (p *instrumentedRatesProvider) GetExchangeRates(ctx context.Context) (types.ExchangeRates, error) {
+	start := time.Now()
+	rates, err := p.provider.GetExchangeRates(ctx)
+	p.metrics.observeUpstream(p.name, "latest", start, err)
+	return rates, err
+}
+
+func (p *instrumentedRatesProvider) GetHistoricalExchangeRates(ctx context.Context, date time.Time) (types.ExchangeRates, error) {
+	start := time.Now()
+	rates, err := p.provider.GetHistoricalExchangeRates(ctx, date)
+	p.metrics.observeUpstream(p.name, "historical", start, err)
+	return rates, err
+}
+
+type instrumentedCryptoRatesProvider struct {
+	provider types.CryptoRatesProvider
+	name     string
+	metrics  *Metrics
+}
+
+func (m *Metrics) InstrumentCryptoRatesProvider(name string, provider types.CryptoRatesProvider) types.CryptoRatesProvider {
+	return &instrumentedCryptoRatesProvider{provider, name, m}
+}
+
+func (p *instrumentedCryptoRatesProvider) GetCryptoExchangeRates(ctx context.Context) (types.CryptoRates, error) {
+	start := time.Now()
+	rates, err := p.provider.GetCryptoExchangeRates(ctx)
+	p.metrics.observeUpstream(p.name, "latest", start, err)
+	return rates, err
+}
+
+type instrumentedNBPRatesProvider struct {
+	provider types.NBPRatesProvider
+	name     string
+	metrics  *Metrics
+}
+
+// InstrumentNBPRatesProvider reports the PLN tables under their own
+// operations, apart from the mid rates the same provider serves as a rates
+// provider.
+func (m *Metrics) InstrumentNBPRatesProvider(name string, provider types.NBPRatesProvider) types.NBPRatesProvider {
+	return &instrumentedNBPRatesProvider{provider, name, m}
+}
+
+func (p *instrumentedNBPRatesProvider) GetBidAskRates(ctx context.Context) (types.BidAskRates, error) {
+	start := time.Now()
+	rates, err := p.provider.GetBidAskRates(ctx)
+	p.metrics.observeUpstream(p.name, "bid_ask", start, err)
+	return rates, err
+}
+
+func (p *instrumentedNBPRatesProvider) GetHistoricalBidAskRates(ctx context.Context, date time.Time) (types.BidAskRates, error) {
+	start := time.Now()
+	rates, err := p.provider.GetHistoricalBidAskRates(ctx, date)
+	p.metrics.observeUpstream(p.name, "historical_bid_ask", start, err)
+	return rates, err
+}
+
+func (p *instrumentedNBPRatesProvider) GetTaxRate(ctx context.Context, currency string, date time.Time) (types.TaxRate, error) {
+	start := time.Now()
+	rate, err := p.provider.GetTaxRate(ctx, currency, date)
+	p.metrics.observeUpstream(p.name, "tax_rate", start, err)
+	return rate, err
+}
+
+func (m *Metrics) observeUpstream(provider, operation string, start time.Time, err error) {
+	m.upstreamRequests.WithLabelValues(provider, operation).Inc()
+	m.upstreamDuration.WithLabelValues(provider, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.upstreamErrors.WithLabelValues(provider, operation).Inc()
+	}
+}
+
+// instrumentedConverter counts successful conversions per pair. Only pairs
+// the converter accepted are counted, which keeps the label set bounded by
+// the known currencies.
+type instrumentedConverter struct {
+	types.Converter
+	metrics *Metrics
+}
+
+func (m *Metrics) InstrumentConverter(converter types.Converter) types.Converter {
+	return &instrumentedConverter{converter, m}
+}
+
+func (c *instrumentedConverter) ExchangeCurrencies(ctx context.Context, from, to string, amount decimal.Decimal) (types.ExchangedCurrency, error) {
+	exchanged, err := c.Converter.ExchangeCurrencies(ctx, from, to, amount)
+	if err == nil {
+		c.metrics.conversions.WithLabelValues(exchanged.From, exchanged.To).Inc()
+	}
+	return exchanged, err
+}
+
+func (c *instrumentedConverter) ConvertCurrencies(ctx context.Context, from, to string, amount decimal.Decimal) (types.ConvertedAmount, error) {
+	converted, err := c.Converter.ConvertCurrencies(ctx, from, to, amount)
+	if err == nil {
+		c.metrics.conversions.WithLabelValues(converted.From, converted.To).Inc()
+	}
+	return converted, err
+}
+
+func (c *instrumentedConverter) ExchangeCurrenciesBatch(ctx context.Context, items []types.BatchConversionItem) (types.BatchConversions, error) {
+	batch, err := c.Converter.ExchangeCurrenciesBatch(ctx, items)
+	if err != nil {
+		return batch, err
+	}
+	for _, conversion := range batch.Conversions {
+		if conversion.Err == nil {
+			c.metrics.conversions.WithLabelValues(conversion.Result.From, conversion.Result.To).Inc()
+		}
+	}
+	return batch, nil
+}
@@ -0,0 +1,154 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	ratescache "github.com/wojcikp/currency-converter/internal/rates_cache"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+const namespace = "currency_converter"
+
+// unmatchedRoute labels requests that did not match any route, so that random
+// paths do not create new series.
+const unmatchedRoute = "unmatched"
+
+// Metrics owns a dedicated registry, so that several servers can live in one
+// process (as they do in tests) without clashing on the default registry.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests     *prometheus.CounterVec
+	httpDuration     *prometheus.HistogramVec
+	upstreamRequests *prometheus.CounterVec
+	upstreamErrors   *prometheus.CounterVec
+	upstreamDuration *prometheus.HistogramVec
+	conversions      *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests served, by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency, by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		upstreamRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upstream_requests_total",
+			Help:      "Calls to upstream rates providers, by provider and operation.",
+		}, []string{"provider", "operation"}),
+		upstreamErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upstream_errors_total",
+			Help:      "Failed calls to upstream rates providers, by provider and operation.",
+		}, []string{"provider", "operation"}),
+		upstreamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "upstream_request_duration_seconds",
+			Help:      "Upstream rates provider latency, by provider and operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"provider", "operation"}),
+		conversions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "conversions_total",
+			Help:      "Successful conversions, by currency pair.",
+		}, []string{"from", "to"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.upstreamRequests,
+		m.upstreamErrors,
+		m.upstreamDuration,
+		m.conversions,
+	)
+	return m
+}
+
+// Handler serves the registry in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// GinMiddleware records every request under its route template rather than
+// the raw path, e.g. /quotes/:id/execute.
+func (m *Metrics) GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		m.httpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		m.httpDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// RegisterRatesCache exposes the cache hit and miss counters and the age of
+// the snapshots the cache currently serves.
+func (m *Metrics) RegisterRatesCache(cache *ratescache.CachedRatesProvider) {
+	m.registry.MustRegister(&ratesCacheCollector{cache: cache, now: time.Now})
+}
+
+var (
+	cacheRequestsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "rates_cache", "requests_total"),
+		"Rates cache lookups, by cache and result (hit or miss).",
+		[]string{"cache", "result"}, nil,
+	)
+	snapshotAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "rates", "snapshot_age_seconds"),
+		"Age of the rates snapshot currently served, by asset type. Absent until the first snapshot is loaded.",
+		[]string{"type"}, nil,
+	)
+)
+
+// ratesCacheCollector reads the cache on every scrape instead of mirroring its
+// state into gauges.
+type ratesCacheCollector struct {
+	cache *ratescache.CachedRatesProvider
+	now   func() time.Time
+}
+
+func (c *ratesCacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheRequestsDesc
+	ch <- snapshotAgeDesc
+}
+
+func (c *ratesCacheCollector) Collect(ch chan<- prometheus.Metric) {
+	for cache, stats := range map[string]ratescache.CacheStats{
+		"latest":     c.cache.LatestStats(),
+		"historical": c.cache.HistoricalStats(),
+	} {
+		ch <- prometheus.MustNewConstMetric(cacheRequestsDesc, prometheus.CounterValue, float64(stats.Hits), cache, "hit")
+		ch <- prometheus.MustNewConstMetric(cacheRequestsDesc, prometheus.CounterValue, float64(stats.Misses), cache, "miss")
+	}
+
+	fiat, crypto := c.cache.SnapshotTimes()
+	now := c.now()
+	for assetType, timestamp := range map[string]time.Time{types.AssetTypeFiat: fiat, types.AssetTypeCrypto: crypto} {
+		if timestamp.IsZero() {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(snapshotAgeDesc, prometheus.GaugeValue, now.Sub(timestamp).Seconds(), assetType)
+	}
+}
@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/shopspring/decimal"
+	currencyconverter "github.com/wojcikp/currency-converter/internal/currency_converter"
+	exchangeratesprovider "github.com/wojcikp/currency-converter/internal/exchange_rates_provider"
+	"github.com/wojcikp/currency-converter/internal/fees"
+	ratescache "github.com/wojcikp/currency-converter/internal/rates_cache"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+type failingProvider struct{}
+
+func (failingProvider) GetExchangeRates(ctx context.Context) (types.ExchangeRates, error) {
+	return types.ExchangeRates{}, errors.New("upstream down")
+}
+
+func (failingProvider) GetHistoricalExchangeRates(ctx context.Context, date time.Time) (types.ExchangeRates, error) {
+	return types.ExchangeRates{}, errors.New("upstream down")
+}
+
+func (failingProvider) GetBidAskRates(ctx context.Context) (types.BidAskRates, error) {
+	return types.BidAskRates{}, errors.New("upstream down")
+}
+
+func (failingProvider) GetHistoricalBidAskRates(ctx context.Context, date time.Time) (types.BidAskRates, error) {
+	return types.BidAskRates{}, errors.New("upstream down")
+}
+
+func (failingProvider) GetTaxRate(ctx context.Context, currency string, date time.Time) (types.TaxRate, error) {
+	return types.TaxRate{}, errors.New("upstream down")
+}
+
+func TestGinMiddleware(t *testing.T) {
+	m := New()
+	router := gin.New()
+	router.Use(m.GinMiddleware())
+	router.POST("/quotes/:id/execute", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/quotes/a/execute", "/quotes/b/execute", "/unknown"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", path, nil))
+	}
+
+	cases := []struct {
+		route  string
+		status string
+		want   float64
+	}{
+		{route: "/quotes/:id/execute", status: "200", want: 2},
+		{route: unmatchedRoute, status: "404", want: 1},
+	}
+	for _, tc := range cases {
+		got := testutil.ToFloat64(m.httpRequests.WithLabelValues("POST", tc.route, tc.status))
+		if got != tc.want {
+			t.Errorf("requests{route=%s,status=%s}=%v, want %v", tc.route, tc.status, got, tc.want)
+		}
+	}
+	if got := testutil.CollectAndCount(m.httpDuration); got != 2 {
+		t.Errorf("duration series=%d, want 2", got)
+	}
+}
+
+func TestInstrumentRatesProvider(t *testing.T) {
+	m := New()
+	ok := m.InstrumentRatesProvider("mock", exchangeratesprovider.NewExchangeRatesProviderMock())
+	failing := m.InstrumentRatesProvider("failing", failingProvider{})
+
+	ok.GetExchangeRates(context.Background())
+	ok.GetHistoricalExchangeRates(context.Background(), time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC))
+	failing.GetExchangeRates(context.Background())
+	failing.GetExchangeRates(context.Background())
+	nbp := m.InstrumentNBPRatesProvider("nbp", failingProvider{})
+	nbp.GetBidAskRates(context.Background())
+	nbp.GetTaxRate(context.Background(), "EUR", time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC))
+
+	cases := []struct {
+		provider   string
+		operation  string
+		wantCalls  float64
+		wantErrors float64
+	}{
+		{provider: "mock", operation: "latest", wantCalls: 1, wantErrors: 0},
+		{provider: "mock", operation: "historical", wantCalls: 1, wantErrors: 0},
+		{provider: "failing", operation: "latest", wantCalls: 2, wantErrors: 2},
+		{provider: "nbp", operation: "bid_ask", wantCalls: 1, wantErrors: 1},
+		{provider: "nbp", operation: "historical_bid_ask", wantCalls: 0, wantErrors: 0},
+		{provider: "nbp", operation: "tax_rate", wantCalls: 1, wantErrors: 1},
+	}
+	for _, tc := range cases {
+		if got := testutil.ToFloat64(m.upstreamRequests.WithLabelValues(tc.provider, tc.operation)); got != tc.wantCalls {
+			t.Errorf("%s %s calls=%v, want %v", tc.provider, tc.operation, got, tc.wantCalls)
+		}
+		if got := testutil.ToFloat64(m.upstreamErrors.WithLabelValues(tc.provider, tc.operation)); got != tc.wantErrors {
+			t.Errorf("%s %s errors=%v, want %v", tc.provider, tc.operation, got, tc.wantErrors)
+		}
+	}
+}
+
+func TestInstrumentConverter(t *testing.T) {
+	m := New()
+	provider := exchangeratesprovider.NewExchangeRatesProviderMock()
+	converter := m.InstrumentConverter(currencyconverter.NewConverter(provider, provider, fees.Schedule{}))
+	ctx := context.Background()
+
+	converter.ExchangeCurrencies(ctx, "USDT", "WBTC", decimal.NewFromInt(1))
+	converter.ConvertCurrencies(ctx, "USD", "EUR", decimal.NewFromInt(10))
+	converter.ConvertCurrencies(ctx, "USD", "XXX", decimal.NewFromInt(10))
+	converter.ExchangeCurrenciesBatch(ctx, []types.BatchConversionItem{
+		{ID: "1", From: "USD", To: "EUR", Amount: decimal.NewFromInt(1)},
+		{ID: "2", From: "USD", To: "XXX", Amount: decimal.NewFromInt(1)},
+	})
+
+	cases := []struct {
+		from string
+		to   string
+		want float64
+	}{
+		{from: "USDT", to: "WBTC", want: 1},
+		{from: "USD", to: "EUR", want: 2},
+	}
+	for _, tc := range cases {
+		if got := testutil.ToFloat64(m.conversions.WithLabelValues(tc.from, tc.to)); got != tc.want {
+			t.Errorf("conversions{%s/%s}=%v, want %v", tc.from, tc.to, got, tc.want)
+		}
+	}
+	if got := testutil.CollectAndCount(m.conversions); got != 2 {
+		t.Errorf("conversion series=%d, want 2 (failed pairs are not counted)", got)
+	}
+}
+
+func TestRatesCacheMetrics(t *testing.T) {
+	m := New()
+	provider := exchangeratesprovider.NewExchangeRatesProviderMock()
+	cache := ratescache.NewCachedRatesProvider(provider, provider, time.Hour)
+	cache.Start(context.Background())
+	defer cache.Stop()
+	m.RegisterRatesCache(cache)
+
+	cache.GetExchangeRates(context.Background())
+	cache.GetCryptoExchangeRates(context.Background())
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(w.Body)
+	if err != nil {
+		t.Fatalf("cannot read metrics: %v", err)
+	}
+
+	for _, want := range []string{
+		`currency_converter_rates_cache_requests_total{cache="latest",result="hit"} 2`,
+		`currency_converter_rates_cache_requests_total{cache="historical",result="miss"} 0`,
+		`currency_converter_rates_snapshot_age_seconds{type="fiat"}`,
+		`currency_converter_rates_snapshot_age_seconds{type="crypto"}`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics output does not contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package nbpprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+const (
+	providerName   = "nbp"
+	DefaultBaseURL = "https://api.nbp.pl/api"
+	DefaultTimeout = 10 * time.Second
+	// lookbackDays bounds the range queried for a past date. NBP does not
+	// publish on weekends and Polish public holidays, and table B only once a
+	// week, so two weeks always contain a published table.
+	lookbackDays = 14
+)
+
+const (
+	TableA = "A"
+	TableB = "B"
+	TableC = "C"
+)
+
+// NBPRatesProvider serves Narodowy Bank Polski rates. Mid rates from tables A
+// and B are quoted in PLN and rebased to the USD quotes used across the app;
+// bid/ask rates from table C and tax rule rates are served in PLN as
+// types.NBPRatesProvider.
+type NBPRatesProvider struct {
+	baseURL    string
+	tables     []string
+	httpClient *http.Client
+}
+
+type Option func(*NBPRatesProvider)
+
+func WithBaseURL(baseURL string) Option {
+	return func(p *NBPRatesProvider) {
+		p.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithTables sets the mid rate tables merged into a snapshot, table A takes
+// precedence over table B for currencies present in both.
+func WithTables(tables ...string) Option {
+	return func(p *NBPRatesProvider) {
+		p.tables = tables
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *NBPRatesProvider) {
+		p.httpClient.Timeout = timeout
+	}
+}
+
+func WithTransport(transport http.RoundTripper) Option {
+	return func(p *NBPRatesProvider) {
+		p.httpClient.Transport = transport
+	}
+}
+
+type table struct {
+	Table         string `json:"table"`
+	No            string `json:"no"`
+	EffectiveDate string `json:"effectiveDate"`
+	Rates         []struct {
+		Code string          `json:"code"`
+		Mid  decimal.Decimal `json:"mid"`
+		Bid  decimal.Decimal `json:"bid"`
+		Ask  decimal.Decimal `json:"ask"`
+	} `json:"rates"`
+}
+
+func NewNBPRatesProvider(opts ...Option) (*NBPRatesProvider, error) {
+	p := &NBPRatesProvider{
+		baseURL:    DefaultBaseURL,
+		tables:     []string{TableA},
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if _, err := url.ParseRequestURI(p.baseURL); err != nil {
+		return nil, fmt.Errorf("invalid nbp base url: %s, err: %w", p.baseURL, err)
+	}
+	if len(p.tables) == 0 {
+		return nil, errors.New("no nbp mid rate tables configured")
+	}
+	tables := make([]string, 0, len(p.tables))
+	for _, t := range p.tables {
+		t = strings.ToUpper(t)
+		if t != TableA && t != TableB {
+			return nil, fmt.Errorf("nbp table %s does not publish mid rates, expected %s or %s", t, TableA, TableB)
+		}
+		tables = append(tables, t)
+	}
+	p.tables = tables
+	return p, nil
+}
+
+func (p *NBPRatesProvider) GetExchangeRates(ctx context.Context) (types.ExchangeRates, error) {
+	var tables []table
+	for _, t := range p.tables {
+		latest, err := p.fetchTable(ctx, fmt.Sprintf("exchangerates/tables/%s/", t))
+		if err != nil {
+			return types.ExchangeRates{}, err
+		}
+		tables = append(tables, latest)
+	}
+	return rebaseToUSD(tables)
+}
+
+// GetHistoricalExchangeRates returns the tables published on date, or on the
+// last business day before it when NBP did not publish that day.
+func (p *NBPRatesProvider) GetHistoricalExchangeRates(ctx context.Context, date time.Time) (types.ExchangeRates, error) {
+	var tables []table
+	for _, t := range p.tables {
+		published, err := p.fetchTableOnOrBefore(ctx, t, date)
+		if err != nil {
+			return types.ExchangeRates{}, err
+		}
+		tables = append(tables, published)
+	}
+	return rebaseToUSD(tables)
+}
+
+// GetTaxRate applies the tax rule: an amount dated D is converted with the mid
+// rate from the last table published on a business day before D. Tables are
+// searched in the configured order.
+func (p *NBPRatesProvider) GetTaxRate(ctx context.Context, currency string, date time.Time) (types.TaxRate, error) {
+	for _, t := range p.tables {
+		published, err := p.fetchTableOnOrBefore(ctx, t, date.AddDate(0, 0, -1))
+		if err != nil {
+			return types.TaxRate{}, err
+		}
+		for _, rate := range published.Rates {
+			if rate.Code != currency || !rate.Mid.IsPositive() {
+				continue
+			}
+			effectiveDate, err := parseEffectiveDate(published)
+			if err != nil {
+				return types.TaxRate{}, err
+			}
+			return types.TaxRate{
+				Currency:      currency,
+				Date:          date,
+				Rate:          rate.Mid,
+				Table:         published.No,
+				EffectiveDate: effectiveDate,
+			}, nil
+		}
+	}
+	return types.TaxRate{}, &types.UnknownCurrencyError{Currency: currency, Source: providerName}
+}
+
+func (p *NBPRatesProvider) GetBidAskRates(ctx context.Context) (types.BidAskRates, error) {
+	latest, err := p.fetchTable(ctx, fmt.Sprintf("exchangerates/tables/%s/", TableC))
+	if err != nil {
+		return types.BidAskRates{}, err
+	}
+	return toBidAsk(latest)
+}
+
+func (p *NBPRatesProvider) GetHistoricalBidAskRates(ctx context.Context, date time.Time) (types.BidAskRates, error) {
+	published, err := p.fetchTableOnOrBefore(ctx, TableC, date)
+	if err != nil {
+		return types.BidAskRates{}, err
+	}
+	return toBidAsk(published)
+}
+
+func (p *NBPRatesProvider) fetchTableOnOrBefore(ctx context.Context, tableName string, date time.Time) (table, error) {
+	start := date.AddDate(0, 0, -lookbackDays).Format(time.DateOnly)
+	end := date.Format(time.DateOnly)
+	tables, err := p.fetchTables(ctx, fmt.Sprintf("exchangerates/tables/%s/%s/%s/", tableName, start, end))
+	if err != nil {
+		return table{}, err
+	}
+	// ranges are returned oldest first
+	return tables[len(tables)-1], nil
+}
+
+func (p *NBPRatesProvider) fetchTable(ctx context.Context, path string) (table, error) {
+	tables, err := p.fetchTables(ctx, path)
+	if err != nil {
+		return table{}, err
+	}
+	return tables[len(tables)-1], nil
+}
+
+func (p *NBPRatesProvider) fetchTables(ctx context.Context, path string) ([]table, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/%s?format=json", p.baseURL, path), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request err: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, &types.ProviderError{
+			Provider:    providerName,
+			Unavailable: true,
+			Err:         fmt.Errorf("error during nbp api GET, err: %w", err),
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &types.ProviderError{
+			Provider:    providerName,
+			Unavailable: resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError,
+			Err:         fmt.Errorf("unexpected status code %d for %s: %s", resp.StatusCode, path, string(body)),
+		}
+	}
+
+	var tables []table
+	if err := json.NewDecoder(resp.Body).Decode(&tables); err != nil {
+		return nil, &types.ProviderError{Provider: providerName, Err: fmt.Errorf("json decoding error: %w", err)}
+	}
+	if len(tables) == 0 {
+		return nil, &types.ProviderError{Provider: providerName, Err: fmt.Errorf("no tables in nbp response for %s", path)}
+	}
+	return tables, nil
+}
+
+// rebaseToUSD turns PLN prices of one unit into units per 1 USD. The snapshot
+// is dated with the first table, which is table A in the default setup.
+func rebaseToUSD(tables []table) (types.ExchangeRates, error) {
+	date, err := parseEffectiveDate(tables[0])
+	if err != nil {
+		return types.ExchangeRates{}, err
+	}
+
+	plnPrices := make(map[string]decimal.Decimal)
+	for _, t := range tables {
+		for _, rate := range t.Rates {
+			if _, ok := plnPrices[rate.Code]; !ok && rate.Mid.IsPositive() {
+				plnPrices[rate.Code] = rate.Mid
+			}
+		}
+	}
+	plnPerUSD, ok := plnPrices["USD"]
+	if !ok {
+		return types.ExchangeRates{}, &types.ProviderError{Provider: providerName, Err: fmt.Errorf("no USD mid rate in table %s", tables[0].No)}
+	}
+
+	rates := make(map[string]decimal.Decimal, len(plnPrices)+1)
+	for currency, price := range plnPrices {
+		rates[currency] = plnPerUSD.Div(price)
+	}
+	rates["PLN"] = plnPerUSD
+	rates["USD"] = decimal.NewFromInt(1)
+
+	return types.ExchangeRates{Source: providerName, Timestamp: date, Rates: rates}, nil
+}
+
+func toBidAsk(t table) (types.BidAskRates, error) {
+	date, err := parseEffectiveDate(t)
+	if err != nil {
+		return types.BidAskRates{}, err
+	}
+	rates := make(map[string]types.BidAskRate, len(t.Rates))
+	for _, rate := range t.Rates {
+		rates[rate.Code] = types.BidAskRate{Bid: rate.Bid, Ask: rate.Ask}
+	}
+	return types.BidAskRates{Table: t.No, EffectiveDate: date, Rates: rates}, nil
+}
+
+func parseEffectiveDate(t table) (time.Time, error) {
+	date, err := time.Parse(time.DateOnly, t.EffectiveDate)
+	if err != nil {
+		return time.Time{}, &types.ProviderError{Provider: providerName, Err: fmt.Errorf("invalid effective date: %s", t.EffectiveDate)}
+	}
+	return date, nil
+}
@@ -0,0 +1,214 @@
+package nbpprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+// newFixtureServer mimics the NBP tables API on top of the recorded tables in
+// testdata: /exchangerates/tables/{table}/ returns the newest table and
+// /exchangerates/tables/{table}/{start}/{end}/ the tables within the range.
+func newFixtureServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/exchangerates/tables/"), "/"), "/")
+		content, err := os.ReadFile("testdata/table_" + strings.ToLower(parts[0]) + ".json")
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		var tables []map[string]any
+		if err := json.Unmarshal(content, &tables); err != nil {
+			t.Fatalf("invalid fixture: %v", err)
+		}
+
+		var selected []map[string]any
+		switch len(parts) {
+		case 1:
+			selected = tables[len(tables)-1:]
+		case 3:
+			for _, table := range tables {
+				if date := table["effectiveDate"].(string); date >= parts[1] && date <= parts[2] {
+					selected = append(selected, table)
+				}
+			}
+		}
+		if len(selected) == 0 {
+			http.Error(w, "404 NotFound - Not Found - Brak danych", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(selected)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGetExchangeRates(t *testing.T) {
+	provider, err := NewNBPRatesProvider(WithBaseURL(newFixtureServer(t).URL), WithTables("a", "b"))
+	if err != nil {
+		t.Fatalf("NewNBPRatesProvider error: %v", err)
+	}
+
+	rates, err := provider.GetExchangeRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetExchangeRates error: %v", err)
+	}
+	if rates.Source != providerName {
+		t.Errorf("source=%s, want %s", rates.Source, providerName)
+	}
+	if want := time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC); !rates.Timestamp.Equal(want) {
+		t.Errorf("timestamp=%s, want %s", rates.Timestamp, want)
+	}
+
+	want := map[string]string{
+		"USD": "1",
+		"PLN": "3.6164",
+		"EUR": "0.8525424927508899",
+		"JPY": "144.3384553981241269",
+		"AFN": "69.9497098646034816",
+	}
+	for currency, rate := range want {
+		if !rates.Rates[currency].Equal(decimal.RequireFromString(rate)) {
+			t.Errorf("%s=%s, want %s", currency, rates.Rates[currency], rate)
+		}
+	}
+}
+
+func TestGetHistoricalExchangeRates(t *testing.T) {
+	provider, err := NewNBPRatesProvider(WithBaseURL(newFixtureServer(t).URL))
+	if err != nil {
+		t.Fatalf("NewNBPRatesProvider error: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		get     func(context.Context, time.Time) (types.ExchangeRates, error)
+		date    time.Time
+		wantDay time.Time
+		wantEUR string
+	}{
+		{"published day", provider.GetHistoricalExchangeRates, time.Date(2025, time.June, 27, 0, 0, 0, 0, time.UTC), time.Date(2025, time.June, 27, 0, 0, 0, 0, time.UTC), "0.8545578776241076"},
+		{"weekend uses previous business day", provider.GetHistoricalExchangeRates, time.Date(2025, time.June, 29, 0, 0, 0, 0, time.UTC), time.Date(2025, time.June, 27, 0, 0, 0, 0, time.UTC), "0.8545578776241076"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rates, err := tc.get(context.Background(), tc.date)
+			if err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			if !rates.Timestamp.Equal(tc.wantDay) {
+				t.Errorf("timestamp=%s, want %s", rates.Timestamp, tc.wantDay)
+			}
+			if !rates.Rates["EUR"].Equal(decimal.RequireFromString(tc.wantEUR)) {
+				t.Errorf("EUR=%s, want %s", rates.Rates["EUR"], tc.wantEUR)
+			}
+		})
+	}
+
+	_, err = provider.GetHistoricalExchangeRates(context.Background(), time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC))
+	var providerErr *types.ProviderError
+	if !errors.As(err, &providerErr) || providerErr.Unavailable {
+		t.Fatalf("err=%v, want available *types.ProviderError for date without tables", err)
+	}
+}
+
+func TestGetTaxRate(t *testing.T) {
+	provider, err := NewNBPRatesProvider(WithBaseURL(newFixtureServer(t).URL), WithTables(TableA, TableB))
+	if err != nil {
+		t.Fatalf("NewNBPRatesProvider error: %v", err)
+	}
+
+	cases := []struct {
+		name      string
+		currency  string
+		date      time.Time
+		wantTable string
+		wantRate  string
+	}{
+		{"monday uses friday table", "EUR", time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC), "123/A/NBP/2025", "4.2443"},
+		{"friday uses thursday table", "EUR", time.Date(2025, time.June, 27, 0, 0, 0, 0, time.UTC), "122/A/NBP/2025", "4.2504"},
+		{"currency from table b", "AFN", time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC), "025/B/NBP/2025", "0.0517"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rate, err := provider.GetTaxRate(context.Background(), tc.currency, tc.date)
+			if err != nil {
+				t.Fatalf("GetTaxRate error: %v", err)
+			}
+			if rate.Table != tc.wantTable {
+				t.Errorf("table=%s, want %s", rate.Table, tc.wantTable)
+			}
+			if !rate.Rate.Equal(decimal.RequireFromString(tc.wantRate)) {
+				t.Errorf("rate=%s, want %s", rate.Rate, tc.wantRate)
+			}
+			if !rate.Date.Equal(tc.date) {
+				t.Errorf("date=%s, want %s", rate.Date, tc.date)
+			}
+		})
+	}
+
+	_, err = provider.GetTaxRate(context.Background(), "XYZ", time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC))
+	var unknownErr *types.UnknownCurrencyError
+	if !errors.As(err, &unknownErr) {
+		t.Fatalf("err=%v, want *types.UnknownCurrencyError", err)
+	}
+}
+
+func TestGetBidAskRates(t *testing.T) {
+	provider, err := NewNBPRatesProvider(WithBaseURL(newFixtureServer(t).URL))
+	if err != nil {
+		t.Fatalf("NewNBPRatesProvider error: %v", err)
+	}
+
+	latest, err := provider.GetBidAskRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetBidAskRates error: %v", err)
+	}
+	if latest.Table != "124/C/NBP/2025" {
+		t.Errorf("table=%s, want 124/C/NBP/2025", latest.Table)
+	}
+	if usd := latest.Rates["USD"]; !usd.Bid.Equal(decimal.RequireFromString("3.5814")) || !usd.Ask.Equal(decimal.RequireFromString("3.6538")) {
+		t.Errorf("USD=%+v, want bid 3.5814 ask 3.6538", usd)
+	}
+
+	historical, err := provider.GetHistoricalBidAskRates(context.Background(), time.Date(2025, time.June, 29, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("GetHistoricalBidAskRates error: %v", err)
+	}
+	if historical.Table != "123/C/NBP/2025" {
+		t.Errorf("table=%s, want 123/C/NBP/2025", historical.Table)
+	}
+}
+
+func TestNewNBPRatesProviderInvalidTable(t *testing.T) {
+	if _, err := NewNBPRatesProvider(WithTables(TableC)); err == nil {
+		t.Fatal("expected error for table without mid rates")
+	}
+}
+
+func TestGetExchangeRatesServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	provider, err := NewNBPRatesProvider(WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("NewNBPRatesProvider error: %v", err)
+	}
+	_, err = provider.GetExchangeRates(context.Background())
+	var providerErr *types.ProviderError
+	if !errors.As(err, &providerErr) || !providerErr.Unavailable {
+		t.Fatalf("err=%v, want unavailable *types.ProviderError", err)
+	}
+}
@@ -0,0 +1,82 @@
+package quotes
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+// memoryRetention is how long a quote is kept after it expired, so that
+// repeated executions keep returning the original result.
+const memoryRetention = 24 * time.Hour
+
+// MemoryStore evicts quotes in expiry order when new quotes are saved. The
+// creation time of the saved quote serves as the current time, so eviction
+// follows the clock of the service that issues the quotes.
+type MemoryStore struct {
+	mu       sync.Mutex
+	quotes   map[string]types.Quote
+	expiries expiryHeap
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{quotes: make(map[string]types.Quote)}
+}
+
+func (s *MemoryStore) SaveQuote(ctx context.Context, quote types.Quote) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.expiries) > 0 && quote.CreatedAt.Sub(s.expiries[0].expiresAt) > memoryRetention {
+		delete(s.quotes, heap.Pop(&s.expiries).(quoteExpiry).id)
+	}
+	s.quotes[quote.ID] = quote
+	heap.Push(&s.expiries, quoteExpiry{id: quote.ID, expiresAt: quote.ExpiresAt})
+	return nil
+}
+
+func (s *MemoryStore) GetQuote(ctx context.Context, id string) (types.Quote, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	quote, ok := s.quotes[id]
+	return quote, ok, nil
+}
+
+func (s *MemoryStore) MarkQuoteExecuted(ctx context.Context, id string, at time.Time) (types.Quote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	quote, ok := s.quotes[id]
+	if !ok {
+		return types.Quote{}, &types.QuoteNotFoundError{ID: id}
+	}
+	if quote.ExecutedAt == nil {
+		quote.ExecutedAt = &at
+		s.quotes[id] = quote
+	}
+	return quote, nil
+}
+
+type quoteExpiry struct {
+	id        string
+	expiresAt time.Time
+}
+
+// expiryHeap implements heap.Interface with the earliest expiry on top.
+type expiryHeap []quoteExpiry
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h expiryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *expiryHeap) Push(x any) {
+	*h = append(*h, x.(quoteExpiry))
+}
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,102 @@
+package quotes
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+const DefaultTTL = 30 * time.Second
+
+// Store keeps issued quotes. MarkQuoteExecuted must be atomic: when a quote is
+// executed concurrently only the first execution time is kept and every caller
+// gets the same quote back.
+type Store interface {
+	SaveQuote(ctx context.Context, quote types.Quote) error
+	GetQuote(ctx context.Context, id string) (types.Quote, bool, error)
+	MarkQuoteExecuted(ctx context.Context, id string, at time.Time) (types.Quote, error)
+}
+
+type Service struct {
+	converter types.Converter
+	store     Store
+	ttl       time.Duration
+	now       func() time.Time
+}
+
+func NewService(converter types.Converter, store Store, ttl time.Duration) *Service {
+	return &Service{converter, store, ttl, time.Now}
+}
+
+func (s *Service) CreateQuote(ctx context.Context, from, to string, amount decimal.Decimal) (types.Quote, error) {
+	if !amount.IsPositive() {
+		return types.Quote{}, &types.InvalidInputError{Field: "amount", Value: amount.String(), Reason: "must be positive"}
+	}
+	exchanged, err := s.converter.ExchangeCurrencies(ctx, from, to, amount)
+	if err != nil {
+		return types.Quote{}, err
+	}
+	id, err := newQuoteID()
+	if err != nil {
+		return types.Quote{}, err
+	}
+
+	createdAt := s.now().UTC()
+	quote := types.Quote{
+		ID:        id,
+		From:      exchanged.From,
+		To:        exchanged.To,
+		Amount:    amount,
+		Result:    exchanged.Amount,
+		Rate:      exchanged.Rate,
+		MidRate:   exchanged.MidRate,
+		Fee:       exchanged.Fee,
+		NetAmount: exchanged.NetAmount,
+		Path:      exchanged.Path,
+		Source:    exchanged.Source,
+		CreatedAt: createdAt,
+		ExpiresAt: createdAt.Add(s.ttl),
+	}
+	if err := s.store.SaveQuote(ctx, quote); err != nil {
+		return types.Quote{}, fmt.Errorf("error during saving quote, err: %w", err)
+	}
+	return quote, nil
+}
+
+// ExecuteQuote converts at the locked rate. Executing an already executed
+// quote returns the original execution, even after the quote has expired.
+func (s *Service) ExecuteQuote(ctx context.Context, id string) (types.Quote, error) {
+	quote, ok, err := s.store.GetQuote(ctx, id)
+	if err != nil {
+		return types.Quote{}, fmt.Errorf("error during fetching quote, err: %w", err)
+	}
+	if !ok {
+		return types.Quote{}, &types.QuoteNotFoundError{ID: id}
+	}
+	if quote.ExecutedAt != nil {
+		return quote, nil
+	}
+
+	now := s.now().UTC()
+	if !now.Before(quote.ExpiresAt) {
+		return types.Quote{}, &types.QuoteExpiredError{ID: id, ExpiresAt: quote.ExpiresAt}
+	}
+	quote, err = s.store.MarkQuoteExecuted(ctx, id, now)
+	if err != nil {
+		return types.Quote{}, fmt.Errorf("error during executing quote, err: %w", err)
+	}
+	return quote, nil
+}
+
+func newQuoteID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("could not generate quote id, err: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,118 @@
+package quotes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	currencyconverter "github.com/wojcikp/currency-converter/internal/currency_converter"
+	exchangeratesprovider "github.com/wojcikp/currency-converter/internal/exchange_rates_provider"
+	"github.com/wojcikp/currency-converter/internal/fees"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+func newTestService(now *time.Time) *Service {
+	provider := exchangeratesprovider.NewExchangeRatesProviderMock()
+	converter := currencyconverter.NewConverter(provider, provider, fees.Schedule{})
+	service := NewService(converter, NewMemoryStore(), DefaultTTL)
+	service.now = func() time.Time { return *now }
+	return service
+}
+
+func TestQuoteLifecycle(t *testing.T) {
+	now := time.Date(2025, time.June, 30, 12, 0, 0, 0, time.UTC)
+	service := newTestService(&now)
+	ctx := context.Background()
+
+	quote, err := service.CreateQuote(ctx, "EUR", "PLN", decimal.NewFromInt(100))
+	if err != nil {
+		t.Fatalf("CreateQuote error: %v", err)
+	}
+	if !quote.ExpiresAt.Equal(now.Add(DefaultTTL)) {
+		t.Fatalf("expires_at=%s, want %s", quote.ExpiresAt, now.Add(DefaultTTL))
+	}
+
+	now = now.Add(10 * time.Second)
+	executed, err := service.ExecuteQuote(ctx, quote.ID)
+	if err != nil {
+		t.Fatalf("ExecuteQuote error: %v", err)
+	}
+	if executed.ExecutedAt == nil || !executed.ExecutedAt.Equal(now) {
+		t.Fatalf("executed_at=%v, want %s", executed.ExecutedAt, now)
+	}
+	if !executed.Rate.Equal(quote.Rate) || !executed.NetAmount.Equal(quote.NetAmount) {
+		t.Fatalf("executed %+v, want locked rate and amounts of %+v", executed, quote)
+	}
+
+	// executing again after expiry returns the original execution
+	now = now.Add(time.Hour)
+	again, err := service.ExecuteQuote(ctx, quote.ID)
+	if err != nil {
+		t.Fatalf("repeated ExecuteQuote error: %v", err)
+	}
+	if !again.ExecutedAt.Equal(*executed.ExecutedAt) {
+		t.Fatalf("executed_at=%s, want %s", again.ExecutedAt, executed.ExecutedAt)
+	}
+}
+
+func TestExecuteQuoteErrors(t *testing.T) {
+	now := time.Date(2025, time.June, 30, 12, 0, 0, 0, time.UTC)
+	service := newTestService(&now)
+	ctx := context.Background()
+
+	quote, err := service.CreateQuote(ctx, "WBTC", "USD", decimal.NewFromInt(1))
+	if err != nil {
+		t.Fatalf("CreateQuote error: %v", err)
+	}
+	now = quote.ExpiresAt
+
+	var expiredErr *types.QuoteExpiredError
+	if _, err := service.ExecuteQuote(ctx, quote.ID); !errors.As(err, &expiredErr) {
+		t.Fatalf("err=%v, want *types.QuoteExpiredError", err)
+	}
+	var notFoundErr *types.QuoteNotFoundError
+	if _, err := service.ExecuteQuote(ctx, "missing"); !errors.As(err, &notFoundErr) {
+		t.Fatalf("err=%v, want *types.QuoteNotFoundError", err)
+	}
+	var invalidInputErr *types.InvalidInputError
+	if _, err := service.CreateQuote(ctx, "EUR", "PLN", decimal.NewFromInt(-1)); !errors.As(err, &invalidInputErr) {
+		t.Fatalf("err=%v, want *types.InvalidInputError", err)
+	}
+}
+
+func TestMemoryStoreEvictsByServiceClock(t *testing.T) {
+	now := time.Date(2025, time.June, 30, 12, 0, 0, 0, time.UTC)
+	service := newTestService(&now)
+	ctx := context.Background()
+
+	old, err := service.CreateQuote(ctx, "EUR", "PLN", decimal.NewFromInt(100))
+	if err != nil {
+		t.Fatalf("CreateQuote error: %v", err)
+	}
+	if _, err := service.ExecuteQuote(ctx, old.ID); err != nil {
+		t.Fatalf("ExecuteQuote error: %v", err)
+	}
+
+	now = old.ExpiresAt.Add(memoryRetention)
+	recent, err := service.CreateQuote(ctx, "EUR", "PLN", decimal.NewFromInt(100))
+	if err != nil {
+		t.Fatalf("CreateQuote error: %v", err)
+	}
+	if _, err := service.ExecuteQuote(ctx, old.ID); err != nil {
+		t.Fatalf("quote evicted before its retention ended: %v", err)
+	}
+
+	now = now.Add(time.Second)
+	if _, err := service.CreateQuote(ctx, "EUR", "PLN", decimal.NewFromInt(100)); err != nil {
+		t.Fatalf("CreateQuote error: %v", err)
+	}
+	var notFoundErr *types.QuoteNotFoundError
+	if _, err := service.ExecuteQuote(ctx, old.ID); !errors.As(err, &notFoundErr) {
+		t.Fatalf("err=%v, want *types.QuoteNotFoundError for an evicted quote", err)
+	}
+	if _, err := service.ExecuteQuote(ctx, recent.ID); err != nil {
+		t.Fatalf("recent quote evicted: %v", err)
+	}
+}
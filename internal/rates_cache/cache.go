@@ -0,0 +1,257 @@
+package ratescache
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+const historicalFetchTimeout = 30 * time.Second
+
+// CachedRatesProvider serves the last good rates snapshots fetched by a single
+// background goroutine, so upstream is never called from the request path.
+type CachedRatesProvider struct {
+	ratesProvider       types.RatesProvider
+	cryptoRatesProvider types.CryptoRatesProvider
+	refreshInterval     time.Duration
+
+	mu          sync.RWMutex
+	rates       types.ExchangeRates
+	cryptoRates types.CryptoRates
+	// ratesRefreshedAt and cryptoRefreshedAt record the last successful
+	// refreshes, upstream timestamps lag behind for daily publishers.
+	ratesRefreshedAt  time.Time
+	cryptoRefreshedAt time.Time
+
+	historical pastDays[types.ExchangeRates]
+
+	latestStats     cacheCounters
+	historicalStats cacheCounters
+
+	subscribersMu sync.Mutex
+	subscribers   map[chan struct{}]struct{}
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewCachedRatesProvider(
+	ratesProvider types.RatesProvider,
+	cryptoRatesProvider types.CryptoRatesProvider,
+	refreshInterval time.Duration,
+) *CachedRatesProvider {
+	return &CachedRatesProvider{
+		ratesProvider:       ratesProvider,
+		cryptoRatesProvider: cryptoRatesProvider,
+		refreshInterval:     refreshInterval,
+		historical:          newPastDays[types.ExchangeRates](),
+		subscribers:         map[chan struct{}]struct{}{},
+		done:                make(chan struct{}),
+	}
+}
+
+// Start loads the first snapshots synchronously and then keeps refreshing them
+// every refreshInterval until Stop is called.
+func (p *CachedRatesProvider) Start(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.refresh(ctx)
+	go p.run(ctx)
+}
+
+func (p *CachedRatesProvider) Stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+}
+
+func (p *CachedRatesProvider) GetExchangeRates(ctx context.Context) (types.ExchangeRates, error) {
+	if err := ctx.Err(); err != nil {
+		return types.ExchangeRates{}, err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.loadedRates()
+}
+
+func (p *CachedRatesProvider) GetCryptoExchangeRates(ctx context.Context) (types.CryptoRates, error) {
+	if err := ctx.Err(); err != nil {
+		return types.CryptoRates{}, err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.loadedCryptoRates()
+}
+
+// GetSnapshot returns the fiat and crypto snapshots under one read lock, so a
+// refresh cannot swap one of them in between.
+func (p *CachedRatesProvider) GetSnapshot(ctx context.Context) (types.ExchangeRates, types.CryptoRates, error) {
+	if err := ctx.Err(); err != nil {
+		return types.ExchangeRates{}, types.CryptoRates{}, err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	rates, err := p.loadedRates()
+	if err != nil {
+		return types.ExchangeRates{}, types.CryptoRates{}, err
+	}
+	cryptoRates, err := p.loadedCryptoRates()
+	if err != nil {
+		return types.ExchangeRates{}, types.CryptoRates{}, err
+	}
+	return rates, cryptoRates, nil
+}
+
+// loadedRates must be called with mu held.
+func (p *CachedRatesProvider) loadedRates() (types.ExchangeRates, error) {
+	if p.rates.Rates == nil {
+		p.latestStats.misses.Add(1)
+		return types.ExchangeRates{}, &types.ProviderError{
+			Provider:    "rates cache",
+			Unavailable: true,
+			Err:         errors.New("exchange rates snapshot not loaded yet"),
+		}
+	}
+	p.latestStats.hits.Add(1)
+	return p.rates, nil
+}
+
+// loadedCryptoRates must be called with mu held.
+func (p *CachedRatesProvider) loadedCryptoRates() (types.CryptoRates, error) {
+	if p.cryptoRates.Rates == nil {
+		p.latestStats.misses.Add(1)
+		return types.CryptoRates{}, &types.ProviderError{
+			Provider:    "rates cache",
+			Unavailable: true,
+			Err:         errors.New("crypto exchange rates snapshot not loaded yet"),
+		}
+	}
+	p.latestStats.hits.Add(1)
+	return p.cryptoRates, nil
+}
+
+// CacheStats counts lookups served from memory (hits) and lookups that had to
+// wait for upstream or found no snapshot loaded (misses).
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+type cacheCounters struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+func (c *cacheCounters) stats() CacheStats {
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+func (p *CachedRatesProvider) LatestStats() CacheStats {
+	return p.latestStats.stats()
+}
+
+func (p *CachedRatesProvider) HistoricalStats() CacheStats {
+	return p.historicalStats.stats()
+}
+
+// Subscribe returns a channel signalled after every refresh that changed the
+// fiat or crypto rates, and a function that ends the subscription. Signals are
+// coalesced, so a slow subscriber only misses duplicates.
+func (p *CachedRatesProvider) Subscribe() (<-chan struct{}, func()) {
+	updates := make(chan struct{}, 1)
+	p.subscribersMu.Lock()
+	defer p.subscribersMu.Unlock()
+	p.subscribers[updates] = struct{}{}
+	return updates, func() {
+		p.subscribersMu.Lock()
+		defer p.subscribersMu.Unlock()
+		delete(p.subscribers, updates)
+	}
+}
+
+func (p *CachedRatesProvider) notify() {
+	p.subscribersMu.Lock()
+	defer p.subscribersMu.Unlock()
+	for updates := range p.subscribers {
+		select {
+		case updates <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// SnapshotTimes returns the timestamps of the current fiat and crypto
+// snapshots, zero when a snapshot has not been loaded yet.
+func (p *CachedRatesProvider) SnapshotTimes() (fiat, crypto time.Time) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.rates.Rates != nil {
+		fiat = p.rates.Timestamp
+	}
+	if p.cryptoRates.Rates != nil {
+		crypto = p.cryptoRates.Timestamp
+	}
+	return fiat, crypto
+}
+
+// RefreshTimes returns when the fiat and crypto snapshots were last refreshed
+// successfully, zero when a snapshot has not been loaded yet.
+func (p *CachedRatesProvider) RefreshTimes() (fiat, crypto time.Time) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.ratesRefreshedAt, p.cryptoRefreshedAt
+}
+
+func (p *CachedRatesProvider) run(ctx context.Context) {
+	defer close(p.done)
+	ticker := time.NewTicker(p.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.refresh(ctx)
+		}
+	}
+}
+
+func (p *CachedRatesProvider) refresh(ctx context.Context) {
+	rates, err := p.ratesProvider.GetExchangeRates(ctx)
+	if err != nil {
+		logrus.Error("could not refresh exchange rates, serving previous snapshot. err: ", err)
+	}
+	cryptoRates, cryptoErr := p.cryptoRatesProvider.GetCryptoExchangeRates(ctx)
+	if cryptoErr != nil {
+		logrus.Error("could not refresh crypto exchange rates, serving previous snapshot. err: ", cryptoErr)
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	changed := false
+	if err == nil {
+		changed = !maps.EqualFunc(p.rates.Rates, rates.Rates, decimal.Decimal.Equal)
+		p.rates, p.ratesRefreshedAt = rates, now
+	}
+	if cryptoErr == nil {
+		changed = changed || !maps.EqualFunc(p.cryptoRates.Rates, cryptoRates.Rates, sameCryptoRate)
+		p.cryptoRates, p.cryptoRefreshedAt = cryptoRates, now
+	}
+	p.mu.Unlock()
+
+	if changed {
+		p.notify()
+	}
+}
+
+func sameCryptoRate(a, b types.CryptoCurrencyInfo) bool {
+	return a.RateToUSD.Equal(b.RateToUSD) && a.DecimalPlaces == b.DecimalPlaces
+}
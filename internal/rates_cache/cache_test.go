@@ -0,0 +1,393 @@
+package ratescache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+type countingProvider struct {
+	calls           atomic.Int32
+	historicalCalls atomic.Int32
+	inFlight        atomic.Int32
+	maxCalls        atomic.Int32
+	fail            atomic.Bool
+}
+
+func (p *countingProvider) GetExchangeRates(ctx context.Context) (types.ExchangeRates, error) {
+	inFlight := p.inFlight.Add(1)
+	defer p.inFlight.Add(-1)
+	if inFlight > p.maxCalls.Load() {
+		p.maxCalls.Store(inFlight)
+	}
+	calls := p.calls.Add(1)
+	if p.fail.Load() {
+		return types.ExchangeRates{}, errors.New("upstream down")
+	}
+	return types.ExchangeRates{
+		Source:    "counting",
+		Timestamp: time.Now(),
+		Rates:     map[string]decimal.Decimal{"USD": decimal.NewFromInt(1), "EUR": decimal.NewFromInt(int64(calls))},
+	}, nil
+}
+
+func (p *countingProvider) GetHistoricalExchangeRates(ctx context.Context, date time.Time) (types.ExchangeRates, error) {
+	p.historicalCalls.Add(1)
+	time.Sleep(10 * time.Millisecond)
+	if p.fail.Load() {
+		return types.ExchangeRates{}, errors.New("upstream down")
+	}
+	return types.ExchangeRates{
+		Source:    "counting",
+		Timestamp: date,
+		Rates:     map[string]decimal.Decimal{"USD": decimal.NewFromInt(1), "EUR": decimal.NewFromInt(int64(date.Day()))},
+	}, nil
+}
+
+func (p *countingProvider) GetCryptoExchangeRates(ctx context.Context) (types.CryptoRates, error) {
+	if p.fail.Load() {
+		return types.CryptoRates{}, errors.New("upstream down")
+	}
+	return types.CryptoRates{
+		Source:    "counting",
+		Timestamp: time.Now(),
+		Rates:     map[string]types.CryptoCurrencyInfo{"USDT": {DecimalPlaces: 6, RateToUSD: decimal.NewFromInt(1)}},
+	}, nil
+}
+
+func TestCachedRatesProviderServesSnapshot(t *testing.T) {
+	provider := &countingProvider{}
+	cache := NewCachedRatesProvider(provider, provider, time.Hour)
+	cache.Start(context.Background())
+	defer cache.Stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rates, err := cache.GetExchangeRates(context.Background())
+			if err != nil {
+				t.Errorf("GetExchangeRates error: %v", err)
+				return
+			}
+			if !rates.Rates["EUR"].Equal(decimal.NewFromInt(1)) {
+				t.Errorf("EUR=%s, want 1", rates.Rates["EUR"])
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Fatalf("upstream calls=%d, want 1", calls)
+	}
+	cryptoRates, err := cache.GetCryptoExchangeRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetCryptoExchangeRates error: %v", err)
+	}
+	if _, ok := cryptoRates.Rates["USDT"]; !ok {
+		t.Fatal("expected USDT in cached crypto rates")
+	}
+	rates, cryptoRates, err := cache.GetSnapshot(context.Background())
+	if err != nil {
+		t.Fatalf("GetSnapshot error: %v", err)
+	}
+	if !rates.Rates["EUR"].Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("snapshot EUR=%s, want 1", rates.Rates["EUR"])
+	}
+	if _, ok := cryptoRates.Rates["USDT"]; !ok {
+		t.Fatal("expected USDT in the snapshot")
+	}
+}
+
+func TestCachedRatesProviderRefresh(t *testing.T) {
+	provider := &countingProvider{}
+	cache := NewCachedRatesProvider(provider, provider, 10*time.Millisecond)
+	cache.Start(context.Background())
+
+	deadline := time.Now().Add(time.Second)
+	for provider.calls.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cache.Stop()
+
+	calls := provider.calls.Load()
+	if calls < 3 {
+		t.Fatalf("upstream calls=%d, want at least 3", calls)
+	}
+	if max := provider.maxCalls.Load(); max != 1 {
+		t.Fatalf("max concurrent upstream calls=%d, want 1", max)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if after := provider.calls.Load(); after != calls {
+		t.Fatalf("upstream called after Stop: before=%d, after=%d", calls, after)
+	}
+}
+
+func TestCachedRatesProviderKeepsLastGoodSnapshot(t *testing.T) {
+	provider := &countingProvider{}
+	cache := NewCachedRatesProvider(provider, provider, time.Hour)
+	cache.Start(context.Background())
+	defer cache.Stop()
+
+	provider.fail.Store(true)
+	cache.refresh(context.Background())
+
+	rates, err := cache.GetExchangeRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetExchangeRates error: %v", err)
+	}
+	if !rates.Rates["EUR"].Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("EUR=%s, want snapshot from first refresh", rates.Rates["EUR"])
+	}
+}
+
+func TestCachedRatesProviderCanceledContext(t *testing.T) {
+	provider := &countingProvider{}
+	cache := NewCachedRatesProvider(provider, provider, time.Hour)
+	cache.Start(context.Background())
+	defer cache.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cache.GetExchangeRates(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetExchangeRates err=%v, want context.Canceled", err)
+	}
+	if _, err := cache.GetCryptoExchangeRates(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetCryptoExchangeRates err=%v, want context.Canceled", err)
+	}
+	if _, _, err := cache.GetSnapshot(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetSnapshot err=%v, want context.Canceled", err)
+	}
+}
+
+func TestCachedRatesProviderNoSnapshot(t *testing.T) {
+	provider := &countingProvider{}
+	provider.fail.Store(true)
+	cache := NewCachedRatesProvider(provider, provider, time.Hour)
+	cache.Start(context.Background())
+	defer cache.Stop()
+
+	if _, err := cache.GetExchangeRates(context.Background()); err == nil {
+		t.Fatal("expected error when no snapshot was loaded")
+	}
+	if _, err := cache.GetCryptoExchangeRates(context.Background()); err == nil {
+		t.Fatal("expected error when no crypto snapshot was loaded")
+	}
+	if _, _, err := cache.GetSnapshot(context.Background()); err == nil {
+		t.Fatal("expected error when no snapshot was loaded")
+	}
+}
+
+func TestCachedRatesProviderHistorical(t *testing.T) {
+	provider := &countingProvider{}
+	cache := NewCachedRatesProvider(provider, provider, time.Hour)
+	date := time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rates, err := cache.GetHistoricalExchangeRates(context.Background(), date)
+			if err != nil {
+				t.Errorf("GetHistoricalExchangeRates error: %v", err)
+				return
+			}
+			if !rates.Rates["EUR"].Equal(decimal.NewFromInt(30)) {
+				t.Errorf("EUR=%s, want 30", rates.Rates["EUR"])
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := cache.GetHistoricalExchangeRates(context.Background(), date); err != nil {
+		t.Fatalf("GetHistoricalExchangeRates error: %v", err)
+	}
+	if calls := provider.historicalCalls.Load(); calls != 1 {
+		t.Fatalf("upstream historical calls=%d, want 1", calls)
+	}
+
+	today := time.Now().UTC()
+	for i := 0; i < 2; i++ {
+		if _, err := cache.GetHistoricalExchangeRates(context.Background(), today); err != nil {
+			t.Fatalf("GetHistoricalExchangeRates error: %v", err)
+		}
+	}
+	if calls := provider.historicalCalls.Load(); calls != 3 {
+		t.Fatalf("upstream historical calls=%d, want 3 since today is not cached", calls)
+	}
+}
+
+func TestCachedRatesProviderHistoricalErrorNotCached(t *testing.T) {
+	provider := &countingProvider{}
+	provider.fail.Store(true)
+	cache := NewCachedRatesProvider(provider, provider, time.Hour)
+	date := time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC)
+
+	if _, err := cache.GetHistoricalExchangeRates(context.Background(), date); err == nil {
+		t.Fatal("expected error from failing upstream")
+	}
+	provider.fail.Store(false)
+	if _, err := cache.GetHistoricalExchangeRates(context.Background(), date); err != nil {
+		t.Fatalf("GetHistoricalExchangeRates error: %v", err)
+	}
+	if calls := provider.historicalCalls.Load(); calls != 2 {
+		t.Fatalf("upstream historical calls=%d, want 2", calls)
+	}
+}
+
+func TestCachedRatesProviderStats(t *testing.T) {
+	provider := &countingProvider{}
+	provider.fail.Store(true)
+	cache := NewCachedRatesProvider(provider, provider, time.Hour)
+	cache.Start(context.Background())
+	defer cache.Stop()
+
+	if fiat, crypto := cache.SnapshotTimes(); !fiat.IsZero() || !crypto.IsZero() {
+		t.Fatalf("snapshot times=%v, %v, want zero before first snapshot", fiat, crypto)
+	}
+	if fiat, crypto := cache.RefreshTimes(); !fiat.IsZero() || !crypto.IsZero() {
+		t.Fatalf("refresh times=%v, %v, want zero before first snapshot", fiat, crypto)
+	}
+	cache.GetExchangeRates(context.Background())
+
+	provider.fail.Store(false)
+	cache.refresh(context.Background())
+	cache.GetExchangeRates(context.Background())
+	cache.GetCryptoExchangeRates(context.Background())
+	if fiat, crypto := cache.SnapshotTimes(); fiat.IsZero() || crypto.IsZero() {
+		t.Fatalf("snapshot times=%v, %v, want both set after refresh", fiat, crypto)
+	}
+	refreshedAt, _ := cache.RefreshTimes()
+	provider.fail.Store(true)
+	cache.refresh(context.Background())
+	if fiat, _ := cache.RefreshTimes(); !fiat.Equal(refreshedAt) {
+		t.Fatalf("refresh time=%v, want %v kept after a failed refresh", fiat, refreshedAt)
+	}
+	provider.fail.Store(false)
+
+	date := time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if _, err := cache.GetHistoricalExchangeRates(context.Background(), date); err != nil {
+			t.Fatalf("GetHistoricalExchangeRates error: %v", err)
+		}
+	}
+
+	if got, want := cache.LatestStats(), (CacheStats{Hits: 2, Misses: 1}); got != want {
+		t.Errorf("latest stats=%+v, want %+v", got, want)
+	}
+	if got, want := cache.HistoricalStats(), (CacheStats{Hits: 2, Misses: 1}); got != want {
+		t.Errorf("historical stats=%+v, want %+v", got, want)
+	}
+}
+
+func TestCachedRatesProviderSubscribe(t *testing.T) {
+	provider := &countingProvider{}
+	cache := NewCachedRatesProvider(provider, provider, time.Hour)
+	updates, unsubscribe := cache.Subscribe()
+
+	cache.refresh(context.Background())
+	cache.refresh(context.Background())
+	select {
+	case <-updates:
+	default:
+		t.Fatal("expected a signal after rates changed")
+	}
+	select {
+	case <-updates:
+		t.Fatal("expected signals to be coalesced")
+	default:
+	}
+
+	provider.fail.Store(true)
+	cache.refresh(context.Background())
+	select {
+	case <-updates:
+		t.Fatal("unexpected signal after a failed refresh")
+	default:
+	}
+
+	unsubscribe()
+	provider.fail.Store(false)
+	cache.refresh(context.Background())
+	select {
+	case <-updates:
+		t.Fatal("unexpected signal after unsubscribe")
+	default:
+	}
+}
+
+type countingNBPProvider struct {
+	bidAskCalls           atomic.Int32
+	historicalBidAskCalls atomic.Int32
+	taxRateCalls          atomic.Int32
+}
+
+func (p *countingNBPProvider) GetBidAskRates(ctx context.Context) (types.BidAskRates, error) {
+	p.bidAskCalls.Add(1)
+	return types.BidAskRates{Table: "124/C/NBP/2025", Rates: map[string]types.BidAskRate{"EUR": {}}}, nil
+}
+
+func (p *countingNBPProvider) GetHistoricalBidAskRates(ctx context.Context, date time.Time) (types.BidAskRates, error) {
+	p.historicalBidAskCalls.Add(1)
+	return types.BidAskRates{Table: date.Format(time.DateOnly), Rates: map[string]types.BidAskRate{"EUR": {}}}, nil
+}
+
+func (p *countingNBPProvider) GetTaxRate(ctx context.Context, currency string, date time.Time) (types.TaxRate, error) {
+	p.taxRateCalls.Add(1)
+	if currency != "EUR" {
+		return types.TaxRate{}, &types.UnknownCurrencyError{Currency: currency, Source: "nbp"}
+	}
+	return types.TaxRate{Currency: currency, Date: date, Rate: decimal.RequireFromString("4.2443")}, nil
+}
+
+func TestCachedNBPRatesProvider(t *testing.T) {
+	provider := &countingNBPProvider{}
+	cache := NewCachedNBPRatesProvider(provider, time.Hour)
+	cache.Start(context.Background())
+	defer cache.Stop()
+	ctx := context.Background()
+	past := time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC)
+	today := time.Now().UTC()
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.GetBidAskRates(ctx); err != nil {
+			t.Fatalf("GetBidAskRates error: %v", err)
+		}
+		if _, err := cache.GetHistoricalBidAskRates(ctx, past); err != nil {
+			t.Fatalf("GetHistoricalBidAskRates error: %v", err)
+		}
+		if _, err := cache.GetHistoricalBidAskRates(ctx, today); err != nil {
+			t.Fatalf("GetHistoricalBidAskRates error: %v", err)
+		}
+		if rate, err := cache.GetTaxRate(ctx, "EUR", past); err != nil || !rate.Rate.Equal(decimal.RequireFromString("4.2443")) {
+			t.Fatalf("GetTaxRate rate=%s, err=%v, want 4.2443", rate.Rate, err)
+		}
+		var unknownErr *types.UnknownCurrencyError
+		if _, err := cache.GetTaxRate(ctx, "XYZ", past); !errors.As(err, &unknownErr) {
+			t.Fatalf("GetTaxRate err=%v, want *types.UnknownCurrencyError", err)
+		}
+	}
+
+	cases := []struct {
+		name  string
+		calls int32
+		want  int32
+	}{
+		{"latest bid/ask refreshed in the background", provider.bidAskCalls.Load(), 1},
+		{"past and today bid/ask, today is not kept", provider.historicalBidAskCalls.Load(), 1 + 3},
+		{"tax rates and unknown currencies", provider.taxRateCalls.Load(), 2},
+	}
+	for _, tc := range cases {
+		if tc.calls != tc.want {
+			t.Errorf("%s: upstream calls=%d, want %d", tc.name, tc.calls, tc.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package ratescache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+// pastDays caches results of past days forever, since they never change.
+// Concurrent misses for the same key share a single upstream call.
+type pastDays[T any] struct {
+	mu       sync.Mutex
+	results  map[string]*pastDayCall[T]
+	inFlight map[string]*pastDayCall[T]
+}
+
+type pastDayCall[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+func newPastDays[T any]() pastDays[T] {
+	return pastDays[T]{results: map[string]*pastDayCall[T]{}, inFlight: map[string]*pastDayCall[T]{}}
+}
+
+// get serves key from memory or waits for fetch, which runs detached from the
+// request context, so one cancelled caller does not fail the others waiting
+// for the same key. The result, an error included, is kept when keep reports
+// it final.
+func (c *pastDays[T]) get(
+	ctx context.Context,
+	key string,
+	stats *cacheCounters,
+	fetch func(context.Context) (T, error),
+	keep func(error) bool,
+) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	c.mu.Lock()
+	if result, ok := c.results[key]; ok {
+		c.mu.Unlock()
+		stats.hits.Add(1)
+		return result.value, result.err
+	}
+	stats.misses.Add(1)
+	call, ok := c.inFlight[key]
+	if !ok {
+		call = &pastDayCall[T]{done: make(chan struct{})}
+		c.inFlight[key] = call
+		go c.fetch(key, call, fetch, keep)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+func (c *pastDays[T]) fetch(key string, call *pastDayCall[T], fetch func(context.Context) (T, error), keep func(error) bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), historicalFetchTimeout)
+	defer cancel()
+	call.value, call.err = fetch(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.inFlight, key)
+	if keep(call.err) {
+		c.results[key] = call
+	}
+	close(call.done)
+}
+
+// beforeToday reports whether the day of date has ended in UTC, the rates of
+// today may still be published or revised.
+func beforeToday(date time.Time) bool {
+	return date.Format(time.DateOnly) < time.Now().UTC().Format(time.DateOnly)
+}
+
+func (p *CachedRatesProvider) GetHistoricalExchangeRates(ctx context.Context, date time.Time) (types.ExchangeRates, error) {
+	return p.historical.get(ctx, date.Format(time.DateOnly), &p.historicalStats,
+		func(ctx context.Context) (types.ExchangeRates, error) {
+			return p.ratesProvider.GetHistoricalExchangeRates(ctx, date)
+		},
+		func(err error) bool { return err == nil && beforeToday(date) },
+	)
+}
@@ -0,0 +1,134 @@
+package ratescache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+// CachedNBPRatesProvider serves NBP rates quoted in PLN the same way
+// CachedRatesProvider serves the USD snapshots: the latest bid/ask table is
+// refreshed in the background, past tables and tax rates are kept forever.
+type CachedNBPRatesProvider struct {
+	provider        types.NBPRatesProvider
+	refreshInterval time.Duration
+
+	mu     sync.RWMutex
+	bidAsk types.BidAskRates
+
+	historicalBidAsk pastDays[types.BidAskRates]
+	taxRates         pastDays[types.TaxRate]
+
+	latestStats     cacheCounters
+	historicalStats cacheCounters
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewCachedNBPRatesProvider(provider types.NBPRatesProvider, refreshInterval time.Duration) *CachedNBPRatesProvider {
+	return &CachedNBPRatesProvider{
+		provider:         provider,
+		refreshInterval:  refreshInterval,
+		historicalBidAsk: newPastDays[types.BidAskRates](),
+		taxRates:         newPastDays[types.TaxRate](),
+		done:             make(chan struct{}),
+	}
+}
+
+// Start loads the first bid/ask table synchronously and then keeps refreshing
+// it every refreshInterval until Stop is called.
+func (p *CachedNBPRatesProvider) Start(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.refresh(ctx)
+	go p.run(ctx)
+}
+
+func (p *CachedNBPRatesProvider) Stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+}
+
+func (p *CachedNBPRatesProvider) GetBidAskRates(ctx context.Context) (types.BidAskRates, error) {
+	if err := ctx.Err(); err != nil {
+		return types.BidAskRates{}, err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.bidAsk.Rates == nil {
+		p.latestStats.misses.Add(1)
+		return types.BidAskRates{}, &types.ProviderError{
+			Provider:    "nbp cache",
+			Unavailable: true,
+			Err:         errors.New("nbp bid/ask table not loaded yet"),
+		}
+	}
+	p.latestStats.hits.Add(1)
+	return p.bidAsk, nil
+}
+
+// GetHistoricalBidAskRates keeps tables of past days only, the table of today
+// may not be published yet.
+func (p *CachedNBPRatesProvider) GetHistoricalBidAskRates(ctx context.Context, date time.Time) (types.BidAskRates, error) {
+	return p.historicalBidAsk.get(ctx, date.Format(time.DateOnly), &p.historicalStats,
+		func(ctx context.Context) (types.BidAskRates, error) {
+			return p.provider.GetHistoricalBidAskRates(ctx, date)
+		},
+		func(err error) bool { return err == nil && beforeToday(date) },
+	)
+}
+
+// GetTaxRate keeps every answer up to today, it comes from tables published
+// before date. Unknown currencies are kept as well, so that repeating them does
+// not reach upstream.
+func (p *CachedNBPRatesProvider) GetTaxRate(ctx context.Context, currency string, date time.Time) (types.TaxRate, error) {
+	return p.taxRates.get(ctx, currency+"/"+date.Format(time.DateOnly), &p.historicalStats,
+		func(ctx context.Context) (types.TaxRate, error) {
+			return p.provider.GetTaxRate(ctx, currency, date)
+		},
+		func(err error) bool {
+			var unknownCurrencyErr *types.UnknownCurrencyError
+			return (err == nil || errors.As(err, &unknownCurrencyErr)) && !date.After(time.Now().UTC())
+		},
+	)
+}
+
+func (p *CachedNBPRatesProvider) LatestStats() CacheStats {
+	return p.latestStats.stats()
+}
+
+func (p *CachedNBPRatesProvider) HistoricalStats() CacheStats {
+	return p.historicalStats.stats()
+}
+
+func (p *CachedNBPRatesProvider) run(ctx context.Context) {
+	defer close(p.done)
+	ticker := time.NewTicker(p.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.refresh(ctx)
+		}
+	}
+}
+
+func (p *CachedNBPRatesProvider) refresh(ctx context.Context) {
+	bidAsk, err := p.provider.GetBidAskRates(ctx)
+	if err != nil {
+		logrus.Error("could not refresh nbp bid/ask rates, serving previous table. err: ", err)
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bidAsk = bidAsk
+}
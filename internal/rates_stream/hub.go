@@ -0,0 +1,83 @@
+package ratesstream
+
+import "sync"
+
+// Updates signals every change of the rates snapshot.
+type Updates interface {
+	Subscribe() (<-chan struct{}, func())
+}
+
+// Hub keeps track of the open rate streams, so that they can be ended when
+// the server shuts down.
+type Hub struct {
+	updates Updates
+
+	mu            sync.Mutex
+	closed        bool
+	subscriptions map[*Subscription]struct{}
+}
+
+// Subscription delivers a signal on Updates after the snapshot changed. Done
+// is closed once the hub is closed.
+type Subscription struct {
+	Updates <-chan struct{}
+	Done    <-chan struct{}
+
+	hub         *Hub
+	done        chan struct{}
+	unsubscribe func()
+	once        sync.Once
+}
+
+func NewHub(updates Updates) *Hub {
+	return &Hub{updates: updates, subscriptions: map[*Subscription]struct{}{}}
+}
+
+// Subscribe registers a new stream. The caller must Close the subscription
+// once the stream ends.
+func (h *Hub) Subscribe() *Subscription {
+	updates, unsubscribe := h.updates.Subscribe()
+	done := make(chan struct{})
+	sub := &Subscription{Updates: updates, Done: done, hub: h, done: done, unsubscribe: unsubscribe}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		sub.release()
+		return sub
+	}
+	h.subscriptions[sub] = struct{}{}
+	return sub
+}
+
+// Close ends all open streams and rejects new ones.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subscriptions {
+		sub.release()
+		delete(h.subscriptions, sub)
+	}
+}
+
+// Len returns the number of open streams.
+func (h *Hub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscriptions)
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.release()
+	delete(s.hub.subscriptions, s)
+}
+
+func (s *Subscription) release() {
+	s.once.Do(func() {
+		s.unsubscribe()
+		close(s.done)
+	})
+}
@@ -0,0 +1,69 @@
+package ratesstream
+
+import (
+	"sync"
+	"testing"
+)
+
+type fakeUpdates struct {
+	mu          sync.Mutex
+	subscribers int
+}
+
+func (u *fakeUpdates) Subscribe() (<-chan struct{}, func()) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.subscribers++
+	return make(chan struct{}), func() {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		u.subscribers--
+	}
+}
+
+func isClosed(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestHubSubscriptionClose(t *testing.T) {
+	updates := &fakeUpdates{}
+	hub := NewHub(updates)
+
+	first, second := hub.Subscribe(), hub.Subscribe()
+	if hub.Len() != 2 || updates.subscribers != 2 {
+		t.Fatalf("len=%d, upstream subscribers=%d, want 2", hub.Len(), updates.subscribers)
+	}
+	first.Close()
+	first.Close()
+	if hub.Len() != 1 || updates.subscribers != 1 {
+		t.Fatalf("len=%d, upstream subscribers=%d, want 1 after close", hub.Len(), updates.subscribers)
+	}
+	if isClosed(second.Done) {
+		t.Fatal("closing one subscription must not end the others")
+	}
+}
+
+func TestHubClose(t *testing.T) {
+	updates := &fakeUpdates{}
+	hub := NewHub(updates)
+	sub := hub.Subscribe()
+
+	hub.Close()
+	if !isClosed(sub.Done) {
+		t.Fatal("expected open subscription to be done after hub close")
+	}
+	if hub.Len() != 0 || updates.subscribers != 0 {
+		t.Fatalf("len=%d, upstream subscribers=%d, want 0", hub.Len(), updates.subscribers)
+	}
+	sub.Close()
+
+	late := hub.Subscribe()
+	if !isClosed(late.Done) || hub.Len() != 0 || updates.subscribers != 0 {
+		t.Fatal("expected subscription after hub close to be done immediately")
+	}
+}
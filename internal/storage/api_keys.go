@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/wojcikp/currency-converter/internal/auth"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+// SaveAPIKey adds a key or replaces the key with the same id, e.g. to rotate
+// it or to change its limits.
+func (s *SQLiteStore) SaveAPIKey(ctx context.Context, key types.APIKey) error {
+	if err := auth.ValidateKey(key); err != nil {
+		return fmt.Errorf("could not save api key, err: %w", err)
+	}
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO api_keys (id, key_hash, rate_per_second, burst, daily_quota) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET key_hash = excluded.key_hash, rate_per_second = excluded.rate_per_second,
+			burst = excluded.burst, daily_quota = excluded.daily_quota`,
+		key.ID, key.Hash, key.RatePerSecond, key.Burst, key.DailyQuota,
+	)
+	if err != nil {
+		return fmt.Errorf("could not save api key %s, err: %w", key.ID, err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) LookupAPIKey(ctx context.Context, hash string) (types.APIKey, bool, error) {
+	var key types.APIKey
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, key_hash, rate_per_second, burst, daily_quota FROM api_keys WHERE key_hash = ?`, hash,
+	).Scan(&key.ID, &key.Hash, &key.RatePerSecond, &key.Burst, &key.DailyQuota)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.APIKey{}, false, nil
+	}
+	if err != nil {
+		return types.APIKey{}, false, fmt.Errorf("could not query api key, err: %w", err)
+	}
+	return key, true, nil
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+// RecordingRatesProvider saves every snapshot fetched from the wrapped provider
+// and serves historical dates from stored historical snapshots before falling
+// back to upstream.
+type RecordingRatesProvider struct {
+	provider types.RatesProvider
+	store    *SQLiteStore
+}
+
+func NewRecordingRatesProvider(provider types.RatesProvider, store *SQLiteStore) *RecordingRatesProvider {
+	return &RecordingRatesProvider{provider, store}
+}
+
+func (p *RecordingRatesProvider) GetExchangeRates(ctx context.Context) (types.ExchangeRates, error) {
+	rates, err := p.provider.GetExchangeRates(ctx)
+	if err != nil {
+		return types.ExchangeRates{}, err
+	}
+	if err := p.store.SaveExchangeRates(ctx, rates); err != nil {
+		logrus.Error(err)
+	}
+	return rates, nil
+}
+
+func (p *RecordingRatesProvider) GetHistoricalExchangeRates(ctx context.Context, date time.Time) (types.ExchangeRates, error) {
+	// today's snapshots keep changing, only finished days are served locally
+	if date.Format(time.DateOnly) < time.Now().UTC().Format(time.DateOnly) {
+		rates, ok, err := p.store.GetExchangeRatesForDate(ctx, date)
+		if err != nil {
+			logrus.Error(err)
+		}
+		if ok {
+			return rates, nil
+		}
+	}
+
+	rates, err := p.provider.GetHistoricalExchangeRates(ctx, date)
+	if err != nil {
+		return types.ExchangeRates{}, err
+	}
+	if err := p.store.SaveHistoricalExchangeRates(ctx, rates); err != nil {
+		logrus.Error(err)
+	}
+	return rates, nil
+}
+
+type RecordingCryptoRatesProvider struct {
+	provider types.CryptoRatesProvider
+	store    *SQLiteStore
+}
+
+func NewRecordingCryptoRatesProvider(provider types.CryptoRatesProvider, store *SQLiteStore) *RecordingCryptoRatesProvider {
+	return &RecordingCryptoRatesProvider{provider, store}
+}
+
+func (p *RecordingCryptoRatesProvider) GetCryptoExchangeRates(ctx context.Context) (types.CryptoRates, error) {
+	rates, err := p.provider.GetCryptoExchangeRates(ctx)
+	if err != nil {
+		return types.CryptoRates{}, err
+	}
+	if err := p.store.SaveCryptoRates(ctx, rates); err != nil {
+		logrus.Error(err)
+	}
+	return rates, nil
+}
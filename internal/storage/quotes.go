@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+// SaveQuote stores the quote as JSON, the execution time is kept in its own
+// column so that it can be set atomically.
+func (s *SQLiteStore) SaveQuote(ctx context.Context, quote types.Quote) error {
+	quote.ExecutedAt = nil
+	encoded, err := json.Marshal(quote)
+	if err != nil {
+		return fmt.Errorf("could not encode quote %s, err: %w", quote.ID, err)
+	}
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO quotes (id, quote, expires_at) VALUES (?, ?, ?)`,
+		quote.ID, string(encoded), quote.ExpiresAt.UnixMilli(),
+	)
+	if err != nil {
+		return fmt.Errorf("could not save quote %s, err: %w", quote.ID, err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetQuote(ctx context.Context, id string) (types.Quote, bool, error) {
+	var encoded string
+	var executedAt sql.NullInt64
+	err := s.db.QueryRowContext(ctx, `SELECT quote, executed_at FROM quotes WHERE id = ?`, id).Scan(&encoded, &executedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.Quote{}, false, nil
+	}
+	if err != nil {
+		return types.Quote{}, false, fmt.Errorf("could not query quote %s, err: %w", id, err)
+	}
+
+	var quote types.Quote
+	if err := json.Unmarshal([]byte(encoded), &quote); err != nil {
+		return types.Quote{}, false, fmt.Errorf("could not decode quote %s, err: %w", id, err)
+	}
+	if executedAt.Valid {
+		at := time.UnixMilli(executedAt.Int64).UTC()
+		quote.ExecutedAt = &at
+	}
+	return quote, true, nil
+}
+
+// MarkQuoteExecuted keeps the first execution time when called repeatedly.
+func (s *SQLiteStore) MarkQuoteExecuted(ctx context.Context, id string, at time.Time) (types.Quote, error) {
+	_, err := s.db.ExecContext(ctx, `UPDATE quotes SET executed_at = ? WHERE id = ? AND executed_at IS NULL`, at.UnixMilli(), id)
+	if err != nil {
+		return types.Quote{}, fmt.Errorf("could not execute quote %s, err: %w", id, err)
+	}
+	quote, ok, err := s.GetQuote(ctx, id)
+	if err != nil {
+		return types.Quote{}, err
+	}
+	if !ok {
+		return types.Quote{}, &types.QuoteNotFoundError{ID: id}
+	}
+	return quote, nil
+}
@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wojcikp/currency-converter/internal/types"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	kindFiat   = "fiat"
+	kindCrypto = "crypto"
+	// originLatest marks snapshots from live refreshes, originHistorical the
+	// reference rates of a past day returned by a provider's historical endpoint.
+	originLatest     = "latest"
+	originHistorical = "historical"
+	// baseCurrency is the quote currency of every stored snapshot, see types.ExchangeRates.
+	baseCurrency = "USD"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS rate_snapshots (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	kind       TEXT    NOT NULL,
+	source     TEXT    NOT NULL,
+	base       TEXT    NOT NULL,
+	timestamp  INTEGER NOT NULL,
+	fetched_at INTEGER NOT NULL,
+	rates      TEXT    NOT NULL,
+	origin     TEXT    NOT NULL DEFAULT 'latest',
+	UNIQUE (kind, source, timestamp)
+);
+CREATE INDEX IF NOT EXISTS rate_snapshots_kind_timestamp ON rate_snapshots (kind, timestamp);
+CREATE TABLE IF NOT EXISTS quotes (
+	id          TEXT    PRIMARY KEY,
+	quote       TEXT    NOT NULL,
+	expires_at  INTEGER NOT NULL,
+	executed_at INTEGER
+);
+CREATE TABLE IF NOT EXISTS api_keys (
+	id              TEXT    PRIMARY KEY,
+	key_hash        TEXT    NOT NULL UNIQUE,
+	rate_per_second REAL    NOT NULL,
+	burst           INTEGER NOT NULL,
+	daily_quota     INTEGER NOT NULL,
+	CHECK (rate_per_second > 0 AND burst >= 1 AND daily_quota >= 1)
+);
+`
+
+// SQLiteStore persists every fetched fiat and crypto rates snapshot, so that
+// quoted rates can be audited and past dates served without calling upstream.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path))
+	if err != nil {
+		return nil, fmt.Errorf("could not open sqlite database: %s, err: %w", path, err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not create sqlite schema: %s, err: %w", path, err)
+	}
+	return &SQLiteStore{db}, nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// SaveExchangeRates records a snapshot from a live refresh.
+func (s *SQLiteStore) SaveExchangeRates(ctx context.Context, rates types.ExchangeRates) error {
+	return s.save(ctx, kindFiat, originLatest, rates.Source, rates.Timestamp, rates.Rates)
+}
+
+// SaveHistoricalExchangeRates records the reference rates of a past day.
+func (s *SQLiteStore) SaveHistoricalExchangeRates(ctx context.Context, rates types.ExchangeRates) error {
+	return s.save(ctx, kindFiat, originHistorical, rates.Source, rates.Timestamp, rates.Rates)
+}
+
+func (s *SQLiteStore) SaveCryptoRates(ctx context.Context, rates types.CryptoRates) error {
+	return s.save(ctx, kindCrypto, originLatest, rates.Source, rates.Timestamp, rates.Rates)
+}
+
+func (s *SQLiteStore) save(ctx context.Context, kind, origin, source string, timestamp time.Time, rates any) error {
+	encoded, err := json.Marshal(rates)
+	if err != nil {
+		return fmt.Errorf("could not encode %s rates snapshot, err: %w", kind, err)
+	}
+	// the same upstream snapshot is fetched on every refresh until it changes,
+	// a historical fetch of a snapshot already recorded live marks it historical
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO rate_snapshots (kind, source, base, timestamp, fetched_at, rates, origin) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (kind, source, timestamp) DO UPDATE SET origin = excluded.origin, rates = excluded.rates
+		WHERE excluded.origin = 'historical'`,
+		kind, source, baseCurrency, timestamp.Unix(), time.Now().Unix(), string(encoded), origin,
+	)
+	if err != nil {
+		return fmt.Errorf("could not save %s rates snapshot from %s, err: %w", kind, source, err)
+	}
+	return nil
+}
+
+// GetExchangeRatesForDate returns the historical fiat snapshot of the given UTC
+// day. Snapshots recorded by live refreshes are not returned, they depend on
+// when the service happened to run instead of being the day's reference rates.
+func (s *SQLiteStore) GetExchangeRatesForDate(ctx context.Context, date time.Time) (types.ExchangeRates, bool, error) {
+	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	var source, encoded string
+	var timestamp int64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT source, timestamp, rates FROM rate_snapshots
+		WHERE kind = ? AND origin = ? AND timestamp BETWEEN ? AND ? ORDER BY timestamp DESC, id DESC LIMIT 1`,
+		kindFiat, originHistorical, dayStart.Unix(), dayStart.AddDate(0, 0, 1).Add(-time.Second).Unix(),
+	).Scan(&source, &timestamp, &encoded)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.ExchangeRates{}, false, nil
+	}
+	if err != nil {
+		return types.ExchangeRates{}, false, fmt.Errorf("could not query historical fiat rates snapshot, err: %w", err)
+	}
+	snapshot := types.ExchangeRates{Source: source, Timestamp: time.Unix(timestamp, 0).UTC()}
+	if err := json.Unmarshal([]byte(encoded), &snapshot.Rates); err != nil {
+		return types.ExchangeRates{}, false, fmt.Errorf("could not decode historical fiat rates snapshot, err: %w", err)
+	}
+	return snapshot, true, nil
+}
+
+// ListExchangeRates returns fiat snapshots with timestamps in [from, to], oldest first.
+func (s *SQLiteStore) ListExchangeRates(ctx context.Context, from, to time.Time) ([]types.ExchangeRates, error) {
+	var snapshots []types.ExchangeRates
+	err := s.list(ctx, kindFiat, from, to, func(source string, timestamp time.Time, encoded []byte) error {
+		snapshot := types.ExchangeRates{Source: source, Timestamp: timestamp}
+		if err := json.Unmarshal(encoded, &snapshot.Rates); err != nil {
+			return err
+		}
+		snapshots = append(snapshots, snapshot)
+		return nil
+	})
+	return snapshots, err
+}
+
+// ListCryptoRates returns crypto snapshots with timestamps in [from, to], oldest first.
+func (s *SQLiteStore) ListCryptoRates(ctx context.Context, from, to time.Time) ([]types.CryptoRates, error) {
+	var snapshots []types.CryptoRates
+	err := s.list(ctx, kindCrypto, from, to, func(source string, timestamp time.Time, encoded []byte) error {
+		snapshot := types.CryptoRates{Source: source, Timestamp: timestamp}
+		if err := json.Unmarshal(encoded, &snapshot.Rates); err != nil {
+			return err
+		}
+		snapshots = append(snapshots, snapshot)
+		return nil
+	})
+	return snapshots, err
+}
+
+func (s *SQLiteStore) list(
+	ctx context.Context,
+	kind string,
+	from, to time.Time,
+	scan func(source string, timestamp time.Time, encoded []byte) error,
+) error {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT source, timestamp, rates FROM rate_snapshots WHERE kind = ? AND timestamp BETWEEN ? AND ? ORDER BY timestamp, id`,
+		kind, from.Unix(), to.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("could not query %s rates snapshots, err: %w", kind, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var source, encoded string
+		var timestamp int64
+		if err := rows.Scan(&source, &timestamp, &encoded); err != nil {
+			return fmt.Errorf("could not read %s rates snapshot, err: %w", kind, err)
+		}
+		if err := scan(source, time.Unix(timestamp, 0).UTC(), []byte(encoded)); err != nil {
+			return fmt.Errorf("could not decode %s rates snapshot, err: %w", kind, err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("could not query %s rates snapshots, err: %w", kind, err)
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/wojcikp/currency-converter/internal/auth"
+	exchangeratesprovider "github.com/wojcikp/currency-converter/internal/exchange_rates_provider"
+	"github.com/wojcikp/currency-converter/internal/types"
+)
+
+func newTestStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "rates.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore error: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestSQLiteStoreExchangeRates(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	day := time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC)
+
+	snapshots := []types.ExchangeRates{
+		{Source: "oxr", Timestamp: day.Add(10 * time.Hour), Rates: map[string]decimal.Decimal{"USD": decimal.NewFromInt(1), "PLN": decimal.RequireFromString("3.61")}},
+		{Source: "oxr", Timestamp: day.Add(23 * time.Hour), Rates: map[string]decimal.Decimal{"USD": decimal.NewFromInt(1), "PLN": decimal.RequireFromString("3.62")}},
+		{Source: "oxr", Timestamp: day.Add(25 * time.Hour), Rates: map[string]decimal.Decimal{"USD": decimal.NewFromInt(1), "PLN": decimal.RequireFromString("3.63")}},
+	}
+	for _, snapshot := range snapshots {
+		if err := store.SaveExchangeRates(ctx, snapshot); err != nil {
+			t.Fatalf("SaveExchangeRates error: %v", err)
+		}
+	}
+	if err := store.SaveExchangeRates(ctx, snapshots[0]); err != nil {
+		t.Fatalf("SaveExchangeRates of duplicate snapshot error: %v", err)
+	}
+
+	all, err := store.ListExchangeRates(ctx, day, day.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatalf("ListExchangeRates error: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("snapshots=%d, want 3 without duplicates", len(all))
+	}
+
+	if _, ok, err := store.GetExchangeRatesForDate(ctx, day); ok || err != nil {
+		t.Fatalf("GetExchangeRatesForDate with only live snapshots ok=%t, err=%v, want not found", ok, err)
+	}
+
+	// the historical fetch returns a snapshot already recorded live
+	if err := store.SaveHistoricalExchangeRates(ctx, snapshots[1]); err != nil {
+		t.Fatalf("SaveHistoricalExchangeRates error: %v", err)
+	}
+	if err := store.SaveExchangeRates(ctx, snapshots[1]); err != nil {
+		t.Fatalf("SaveExchangeRates of historical snapshot error: %v", err)
+	}
+	got, ok, err := store.GetExchangeRatesForDate(ctx, day)
+	if err != nil || !ok {
+		t.Fatalf("GetExchangeRatesForDate ok=%t, err=%v", ok, err)
+	}
+	if !got.Timestamp.Equal(snapshots[1].Timestamp) || !got.Rates["PLN"].Equal(decimal.RequireFromString("3.62")) {
+		t.Fatalf("got snapshot %+v, want the historical snapshot of the day", got)
+	}
+	if got.Source != "oxr" {
+		t.Fatalf("source=%s, want oxr", got.Source)
+	}
+	if all, _ := store.ListExchangeRates(ctx, day, day.AddDate(0, 0, 2)); len(all) != 3 {
+		t.Fatalf("snapshots=%d, want 3 after marking one historical", len(all))
+	}
+
+	if _, ok, err := store.GetExchangeRatesForDate(ctx, day.AddDate(0, 0, -1)); ok || err != nil {
+		t.Fatalf("GetExchangeRatesForDate for empty day ok=%t, err=%v", ok, err)
+	}
+}
+
+func TestSQLiteStoreCryptoRates(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	provider := exchangeratesprovider.NewExchangeRatesProviderMock()
+
+	rates, err := provider.GetCryptoExchangeRates(ctx)
+	if err != nil {
+		t.Fatalf("GetCryptoExchangeRates error: %v", err)
+	}
+	if err := store.SaveCryptoRates(ctx, rates); err != nil {
+		t.Fatalf("SaveCryptoRates error: %v", err)
+	}
+
+	got, err := store.ListCryptoRates(ctx, rates.Timestamp, rates.Timestamp)
+	if err != nil {
+		t.Fatalf("ListCryptoRates error: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("snapshots=%d, want 1", len(got))
+	}
+	wbtc := got[0].Rates["WBTC"]
+	if wbtc.DecimalPlaces != 8 || !wbtc.RateToUSD.Equal(decimal.RequireFromString("57037.22")) {
+		t.Fatalf("WBTC=%+v, want stored decimal places and rate", wbtc)
+	}
+}
+
+type countingHistoricalProvider struct {
+	*exchangeratesprovider.ExchangeRatesProviderMock
+	historicalCalls int
+}
+
+func (p *countingHistoricalProvider) GetHistoricalExchangeRates(ctx context.Context, date time.Time) (types.ExchangeRates, error) {
+	p.historicalCalls++
+	return p.ExchangeRatesProviderMock.GetHistoricalExchangeRates(ctx, date)
+}
+
+func TestRecordingRatesProvider(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	upstream := &countingHistoricalProvider{ExchangeRatesProviderMock: exchangeratesprovider.NewExchangeRatesProviderMock()}
+	provider := NewRecordingRatesProvider(upstream, store)
+	date := time.Date(2025, time.June, 30, 0, 0, 0, 0, time.UTC)
+
+	// a live snapshot taken on the date is not served as the date's rates
+	if _, err := provider.GetExchangeRates(ctx); err != nil {
+		t.Fatalf("GetExchangeRates error: %v", err)
+	}
+	latest, err := store.ListExchangeRates(ctx, exchangeratesprovider.MockRatesTimestamp, exchangeratesprovider.MockRatesTimestamp)
+	if err != nil {
+		t.Fatalf("ListExchangeRates error: %v", err)
+	}
+	if len(latest) != 1 {
+		t.Fatalf("latest snapshots=%d, want 1 recorded", len(latest))
+	}
+
+	for i := 0; i < 3; i++ {
+		rates, err := provider.GetHistoricalExchangeRates(ctx, date)
+		if err != nil {
+			t.Fatalf("GetHistoricalExchangeRates error: %v", err)
+		}
+		if !rates.Rates["PLN"].Equal(decimal.RequireFromString("3.9")) {
+			t.Fatalf("PLN=%s, want 3.9", rates.Rates["PLN"])
+		}
+	}
+	if upstream.historicalCalls != 1 {
+		t.Fatalf("upstream historical calls=%d, want 1", upstream.historicalCalls)
+	}
+}
+
+func TestSQLiteStoreQuotes(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	createdAt := time.Date(2025, time.June, 30, 12, 0, 0, 0, time.UTC)
+
+	quote := types.Quote{
+		ID:        "q1",
+		From:      "EUR",
+		To:        "PLN",
+		Amount:    decimal.NewFromInt(100),
+		Rate:      decimal.RequireFromString("4.2028"),
+		NetAmount: decimal.RequireFromString("420.28"),
+		Path:      []string{"EUR", "USD", "PLN"},
+		CreatedAt: createdAt,
+		ExpiresAt: createdAt.Add(30 * time.Second),
+	}
+	if err := store.SaveQuote(ctx, quote); err != nil {
+		t.Fatalf("SaveQuote error: %v", err)
+	}
+	if err := store.SaveQuote(ctx, quote); err == nil {
+		t.Fatal("expected error for duplicate quote id")
+	}
+
+	got, ok, err := store.GetQuote(ctx, "q1")
+	if err != nil || !ok {
+		t.Fatalf("GetQuote ok=%t, err=%v", ok, err)
+	}
+	if !got.Rate.Equal(quote.Rate) || !got.ExpiresAt.Equal(quote.ExpiresAt) || got.ExecutedAt != nil {
+		t.Fatalf("got quote %+v, want %+v", got, quote)
+	}
+
+	first, err := store.MarkQuoteExecuted(ctx, "q1", createdAt.Add(5*time.Second))
+	if err != nil {
+		t.Fatalf("MarkQuoteExecuted error: %v", err)
+	}
+	second, err := store.MarkQuoteExecuted(ctx, "q1", createdAt.Add(10*time.Second))
+	if err != nil {
+		t.Fatalf("MarkQuoteExecuted error: %v", err)
+	}
+	if first.ExecutedAt == nil || !second.ExecutedAt.Equal(*first.ExecutedAt) || !first.ExecutedAt.Equal(createdAt.Add(5*time.Second)) {
+		t.Fatalf("executed_at=%v then %v, want first execution kept", first.ExecutedAt, second.ExecutedAt)
+	}
+
+	if _, ok, err := store.GetQuote(ctx, "missing"); ok || err != nil {
+		t.Fatalf("GetQuote of missing quote ok=%t, err=%v", ok, err)
+	}
+}
+
+func TestSQLiteStoreAPIKeys(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	hash, rotatedHash := auth.HashKey("secret"), auth.HashKey("rotated")
+
+	key := types.APIKey{ID: "partner", Hash: hash, RatePerSecond: 2.5, Burst: 5, DailyQuota: 1000}
+	if err := store.SaveAPIKey(ctx, key); err != nil {
+		t.Fatalf("SaveAPIKey error: %v", err)
+	}
+	got, ok, err := store.LookupAPIKey(ctx, hash)
+	if err != nil || !ok {
+		t.Fatalf("LookupAPIKey ok=%t, err=%v", ok, err)
+	}
+	if got != key {
+		t.Fatalf("LookupAPIKey=%+v, want %+v", got, key)
+	}
+
+	rotated := types.APIKey{ID: "partner", Hash: rotatedHash, RatePerSecond: 1, Burst: 1, DailyQuota: 10}
+	if err := store.SaveAPIKey(ctx, rotated); err != nil {
+		t.Fatalf("SaveAPIKey error: %v", err)
+	}
+	if _, ok, err := store.LookupAPIKey(ctx, hash); err != nil || ok {
+		t.Fatalf("LookupAPIKey of rotated key ok=%t, err=%v, want not found", ok, err)
+	}
+	if got, ok, err := store.LookupAPIKey(ctx, rotatedHash); err != nil || !ok || got != rotated {
+		t.Fatalf("LookupAPIKey=%+v, ok=%t, err=%v, want %+v", got, ok, err, rotated)
+	}
+
+	if err := store.SaveAPIKey(ctx, types.APIKey{ID: "broken", Hash: hash, Burst: 1, DailyQuota: 1}); err == nil {
+		t.Fatal("expected error when saving a key without a rate")
+	}
+	_, err = store.db.ExecContext(ctx, `INSERT INTO api_keys (id, key_hash, rate_per_second, burst, daily_quota) VALUES ('broken', ?, 0, 1, 1)`, hash)
+	if err == nil {
+		t.Fatal("expected the CHECK constraint to reject a key inserted by hand without a rate")
+	}
+}
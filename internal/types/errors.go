@@ -0,0 +1,82 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+type InvalidInputError struct {
+	Field  string
+	Value  string
+	Reason string
+}
+
+func (e *InvalidInputError) Error() string {
+	return fmt.Sprintf("invalid %s %q: %s", e.Field, e.Value, e.Reason)
+}
+
+type UnknownCurrencyError struct {
+	Currency string
+	Source   string
+}
+
+func (e *UnknownCurrencyError) Error() string {
+	return fmt.Sprintf("currency: %s not found in %s rates", e.Currency, e.Source)
+}
+
+// ProviderError reports an upstream rates source failure. Unavailable is set when
+// the source could not be reached at all, as opposed to returning a bad response.
+type ProviderError struct {
+	Provider    string
+	Unavailable bool
+	Err         error
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s provider error: %v", e.Provider, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+type QuoteNotFoundError struct {
+	ID string
+}
+
+func (e *QuoteNotFoundError) Error() string {
+	return fmt.Sprintf("quote: %s not found", e.ID)
+}
+
+type QuoteExpiredError struct {
+	ID        string
+	ExpiresAt time.Time
+}
+
+func (e *QuoteExpiredError) Error() string {
+	return fmt.Sprintf("quote: %s expired at %s", e.ID, e.ExpiresAt.Format(time.RFC3339))
+}
+
+type UnauthorizedError struct {
+	Reason string
+}
+
+func (e *UnauthorizedError) Error() string {
+	return fmt.Sprintf("unauthorized: %s", e.Reason)
+}
+
+// RateLimitError is returned when an API key exceeded its request rate or its
+// daily quota. Quota tells the two apart.
+type RateLimitError struct {
+	KeyID      string
+	Quota      bool
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	limit := "rate limit"
+	if e.Quota {
+		limit = "daily quota"
+	}
+	return fmt.Sprintf("api key: %s exceeded its %s, retry after %s", e.KeyID, limit, e.RetryAfter)
+}
@@ -2,18 +2,74 @@ package types
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/shopspring/decimal"
 )
 
 type RatesProvider interface {
-	GetExchangeRates(context.Context) (map[string]decimal.Decimal, error)
-	GetCryptoExchangeRates(ctx context.Context) map[string]CryptoCurrencyInfo
+	GetExchangeRates(context.Context) (ExchangeRates, error)
+	GetHistoricalExchangeRates(ctx context.Context, date time.Time) (ExchangeRates, error)
+}
+
+type CryptoRatesProvider interface {
+	GetCryptoExchangeRates(ctx context.Context) (CryptoRates, error)
+}
+
+// SnapshotProvider is implemented by providers serving fiat and crypto rates
+// that can return both from the same refresh.
+type SnapshotProvider interface {
+	GetSnapshot(ctx context.Context) (ExchangeRates, CryptoRates, error)
+}
+
+// NBPRatesProvider serves the Narodowy Bank Polski rates that are quoted in PLN
+// and not rebased to USD: bid/ask rates from table C and mid rates under the
+// tax rule.
+type NBPRatesProvider interface {
+	GetBidAskRates(ctx context.Context) (BidAskRates, error)
+	GetHistoricalBidAskRates(ctx context.Context, date time.Time) (BidAskRates, error)
+	GetTaxRate(ctx context.Context, currency string, date time.Time) (TaxRate, error)
 }
 
 type Converter interface {
-	GetCurrenciesRates(ctx context.Context, currencies []string) ([]ConvertedRate, error)
-	ConvertCryptoCurrencies(ctx context.Context, from, to string, amount decimal.Decimal) (ExchangedCryptoCurrency, error)
+	GetCurrenciesRates(ctx context.Context, currencies []string) (CurrenciesRates, error)
+	GetHistoricalCurrenciesRates(ctx context.Context, date time.Time, currencies []string) (CurrenciesRates, error)
+	GetRatesMatrix(ctx context.Context, currencies []string, base string) (RatesMatrix, error)
+	GetLatestRates(ctx context.Context, base string, symbols []string) (LatestRates, error)
+	GetCurrencies(ctx context.Context, assetType string) ([]CurrencyInfo, error)
+	GetTimeSeries(ctx context.Context, request TimeSeriesRequest) (TimeSeries, error)
+	ExchangeCurrencies(ctx context.Context, from, to string, amount decimal.Decimal) (ExchangedCurrency, error)
+	ConvertCurrencies(ctx context.Context, from, to string, amount decimal.Decimal) (ConvertedAmount, error)
+	ExchangeCurrenciesBatch(ctx context.Context, items []BatchConversionItem) (BatchConversions, error)
+}
+
+type ReadinessChecker interface {
+	CheckReadiness(ctx context.Context) Readiness
+}
+
+type QuoteService interface {
+	CreateQuote(ctx context.Context, from, to string, amount decimal.Decimal) (Quote, error)
+	ExecuteQuote(ctx context.Context, id string) (Quote, error)
+}
+
+// ExchangeRates is a fiat rates snapshot quoted as units of currency per one USD.
+type ExchangeRates struct {
+	Source    string
+	Timestamp time.Time
+	Rates     map[string]decimal.Decimal
+}
+
+type CryptoRates struct {
+	Source    string
+	Timestamp time.Time
+	Rates     map[string]CryptoCurrencyInfo
+}
+
+type CurrenciesRates struct {
+	Source    string
+	Timestamp time.Time
+	Rates     []ConvertedRate
 }
 
 type ConvertedRate struct {
@@ -22,13 +78,228 @@ type ConvertedRate struct {
 	Rate decimal.Decimal `json:"rate"`
 }
 
-type ExchangedCryptoCurrency struct {
+// LatestRates mirrors the openexchangerates.org latest.json response, so that
+// clients of that API can use this service as a drop-in replacement.
+type LatestRates struct {
+	Source    string                     `json:"-"`
+	Timestamp int64                      `json:"timestamp"`
+	Base      string                     `json:"base"`
+	Rates     map[string]decimal.Decimal `json:"rates"`
+}
+
+// MarshalJSON renders rates as JSON numbers, as openexchangerates.org does,
+// instead of the quoted decimals used by the rest of the API.
+func (r LatestRates) MarshalJSON() ([]byte, error) {
+	rates := make(map[string]json.Number, len(r.Rates))
+	for currency, rate := range r.Rates {
+		rates[currency] = json.Number(rate.String())
+	}
+	return json.Marshal(struct {
+		Timestamp int64                  `json:"timestamp"`
+		Base      string                 `json:"base"`
+		Rates     map[string]json.Number `json:"rates"`
+	}{r.Timestamp, r.Base, rates})
+}
+
+// RatesMatrix holds Values[i][j], the rate from Rows[i] to Columns[j].
+type RatesMatrix struct {
+	Source    string              `json:"source"`
+	Timestamp time.Time           `json:"timestamp"`
+	Rows      []string            `json:"rows"`
+	Columns   []string            `json:"columns"`
+	Values    [][]decimal.Decimal `json:"values"`
+	Base      *RatesMatrixRow     `json:"base,omitempty"`
+}
+
+type RatesMatrixRow struct {
+	Currency string            `json:"currency"`
+	Values   []decimal.Decimal `json:"values"`
+}
+
+// ExchangedCurrency and ConvertedAmount report Rate as the rate applied to the
+// customer, MidRate as the market rate and NetAmount as what is received after
+// Fee is deducted.
+type ExchangedCurrency struct {
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Amount    decimal.Decimal `json:"amount"`
+	Rate      decimal.Decimal `json:"rate"`
+	MidRate   decimal.Decimal `json:"mid_rate"`
+	Fee       decimal.Decimal `json:"fee"`
+	NetAmount decimal.Decimal `json:"net_amount"`
+	Path      []string        `json:"path"`
+	Source    string          `json:"source"`
+}
+
+type ConvertedAmount struct {
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Amount    decimal.Decimal `json:"amount"`
+	Result    decimal.Decimal `json:"result"`
+	Rate      decimal.Decimal `json:"rate"`
+	MidRate   decimal.Decimal `json:"mid_rate"`
+	Fee       decimal.Decimal `json:"fee"`
+	NetAmount decimal.Decimal `json:"net_amount"`
+	Source    string          `json:"source"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+type BatchConversionItem struct {
+	ID     string          `json:"id"`
 	From   string          `json:"from"`
 	To     string          `json:"to"`
 	Amount decimal.Decimal `json:"amount"`
 }
 
+// BatchConversions holds one conversion per requested item, in request order,
+// all computed from the snapshot identified by Source and Timestamp.
+type BatchConversions struct {
+	Source          string
+	Timestamp       time.Time
+	CryptoTimestamp time.Time
+	Conversions     []BatchConversion
+}
+
+// BatchConversion carries either Result or Err.
+type BatchConversion struct {
+	ID     string
+	Result ExchangedCurrency
+	Err    error
+}
+
+// Quote locks the outcome of a conversion until ExpiresAt. ExecutedAt is set
+// once the quote has been executed.
+type Quote struct {
+	ID         string          `json:"id"`
+	From       string          `json:"from"`
+	To         string          `json:"to"`
+	Amount     decimal.Decimal `json:"amount"`
+	Result     decimal.Decimal `json:"result"`
+	Rate       decimal.Decimal `json:"rate"`
+	MidRate    decimal.Decimal `json:"mid_rate"`
+	Fee        decimal.Decimal `json:"fee"`
+	NetAmount  decimal.Decimal `json:"net_amount"`
+	Path       []string        `json:"path"`
+	Source     string          `json:"source"`
+	CreatedAt  time.Time       `json:"created_at"`
+	ExpiresAt  time.Time       `json:"expires_at"`
+	ExecutedAt *time.Time      `json:"executed_at,omitempty"`
+}
+
+const (
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+type TimeSeriesRequest struct {
+	From     string
+	To       string
+	Start    time.Time
+	End      time.Time
+	Interval string
+}
+
+type TimeSeries struct {
+	From     string            `json:"from"`
+	To       string            `json:"to"`
+	Start    string            `json:"start"`
+	End      string            `json:"end"`
+	Interval string            `json:"interval"`
+	Points   []TimeSeriesPoint `json:"points"`
+	Summary  TimeSeriesSummary `json:"summary"`
+}
+
+type TimeSeriesPoint struct {
+	Date string          `json:"date"`
+	Rate decimal.Decimal `json:"rate"`
+}
+
+type TimeSeriesSummary struct {
+	Min           decimal.Decimal `json:"min"`
+	Max           decimal.Decimal `json:"max"`
+	Mean          decimal.Decimal `json:"mean"`
+	Change        decimal.Decimal `json:"change"`
+	ChangePercent decimal.Decimal `json:"change_percent"`
+}
+
 type CryptoCurrencyInfo struct {
+	Name          string
 	DecimalPlaces int
 	RateToUSD     decimal.Decimal
 }
+
+// CryptoToken maps a token symbol to its id in the crypto prices source.
+type CryptoToken struct {
+	Symbol        string
+	SourceID      string
+	Name          string
+	DecimalPlaces int
+}
+
+const (
+	AssetTypeFiat   = "fiat"
+	AssetTypeCrypto = "crypto"
+)
+
+// CurrencyInfo describes a supported asset. MinorUnits, NumericCode and Symbol
+// are set for fiat currencies with ISO 4217 metadata, DecimalPlaces for crypto.
+type CurrencyInfo struct {
+	Code          string `json:"code"`
+	Type          string `json:"type"`
+	Name          string `json:"name,omitempty"`
+	NumericCode   string `json:"numeric_code,omitempty"`
+	MinorUnits    *int   `json:"minor_units,omitempty"`
+	Symbol        string `json:"symbol,omitempty"`
+	DecimalPlaces *int   `json:"decimal_places,omitempty"`
+}
+
+// BidAskRate is the PLN price of one unit of a currency from NBP table C.
+type BidAskRate struct {
+	Bid decimal.Decimal `json:"bid"`
+	Ask decimal.Decimal `json:"ask"`
+}
+
+type BidAskRates struct {
+	Table         string                `json:"table"`
+	EffectiveDate time.Time             `json:"effective_date"`
+	Rates         map[string]BidAskRate `json:"rates"`
+}
+
+// TaxRate is the NBP mid rate in PLN for an amount dated Date, taken from the
+// last table published on a business day before Date.
+type TaxRate struct {
+	Currency      string          `json:"currency"`
+	Date          time.Time       `json:"date"`
+	Rate          decimal.Decimal `json:"rate"`
+	Table         string          `json:"table"`
+	EffectiveDate time.Time       `json:"effective_date"`
+}
+
+// APIKey grants access to the API. Only the SHA-256 hash of the key is stored.
+// RatePerSecond and Burst describe the token bucket, DailyQuota the number of
+// requests allowed per UTC day.
+type APIKey struct {
+	ID            string  `json:"id"`
+	Hash          string  `json:"hash"`
+	RatePerSecond float64 `json:"rate_per_second"`
+	Burst         int     `json:"burst"`
+	DailyQuota    int     `json:"daily_quota"`
+}
+
+// Readiness reports whether the service can serve requests, with the state of
+// every dependency keyed by its name.
+type Readiness struct {
+	Ready  bool                        `json:"ready"`
+	Checks map[string]DependencyStatus `json:"checks"`
+}
+
+// DependencyStatus carries the last successful refresh as Timestamp and the
+// time since it as AgeSeconds for rates snapshots, and Error whenever the
+// dependency is not healthy.
+type DependencyStatus struct {
+	Status     string     `json:"status"`
+	Timestamp  *time.Time `json:"timestamp,omitempty"`
+	AgeSeconds *int64     `json:"age_seconds,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}